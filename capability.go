@@ -0,0 +1,194 @@
+package twitchirc
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// capabilityTimeout is the time Client.Connect waits for the replies to the capability requests.
+const capabilityTimeout = time.Second * 10
+
+// The capabilities supported by twitch.
+const (
+	CapabilityTags       = "twitch.tv/tags"
+	CapabilityCommands   = "twitch.tv/commands"
+	CapabilityMembership = "twitch.tv/membership"
+)
+
+// ErrCapabilityRejected is returned if twitch rejected a required capability.
+var ErrCapabilityRejected = errors.New("twitchirc: required capability was rejected")
+
+// Capability is an IRCv3 capability requested after the login.
+type Capability struct {
+	Name string
+
+	// Required makes the connection fail if the server rejects the capability.
+	// Client.Connect waits for the replies of the server if any capability is required.
+	Required bool
+}
+
+// capabilityRequests returns the capabilities requested by the config, one slice for every CAP REQ.
+//
+// The server accepts or rejects a CAP REQ as a whole, so the Capture flags and the required
+// capabilities are requested together and every optional capability is requested on its own.
+// An unknown optional capability can't get the other capabilities rejected this way.
+func (conf *Config) capabilityRequests() [][]string {
+	captures := []string{}
+	if conf.CaptureTags {
+		captures = append(captures, CapabilityTags)
+	}
+	if conf.CaptureCommands {
+		captures = append(captures, CapabilityCommands)
+	}
+	if conf.CaptureMembership {
+		captures = append(captures, CapabilityMembership)
+	}
+
+	seen := make(map[string]bool)
+	for _, name := range captures {
+		seen[name] = true
+	}
+
+	var optional []string
+
+	for _, capability := range conf.Capabilities {
+		if capability.Name == "" || seen[capability.Name] {
+			continue
+		}
+
+		seen[capability.Name] = true
+
+		if capability.Required {
+			captures = append(captures, capability.Name)
+		} else {
+			optional = append(optional, capability.Name)
+		}
+	}
+
+	var requests [][]string
+	if len(captures) > 0 {
+		requests = append(requests, captures)
+	}
+
+	for _, name := range optional {
+		requests = append(requests, []string{name})
+	}
+
+	return requests
+}
+
+// requiredCapabilities returns the names of the capabilities which must not be rejected.
+func (conf *Config) requiredCapabilities() map[string]bool {
+	required := make(map[string]bool)
+
+	for _, capability := range conf.Capabilities {
+		if capability.Required {
+			required[capability.Name] = true
+		}
+	}
+
+	return required
+}
+
+// updateCapabilities records the capabilities of a CAP ACK or CAP NAK.
+// It returns an error if a required capability was rejected.
+// The caller must hold the lock of the readiness.
+func (c *Connection) updateCapabilities(msg *Message) error {
+	rd := &c.readiness
+
+	if len(msg.Params) < 3 {
+		return nil
+	}
+
+	names := strings.Fields(msg.Params[2])
+
+	switch msg.Params[1] {
+	case "ACK":
+		if rd.capabilities == nil {
+			rd.capabilities = make(map[string]bool)
+		}
+
+		for _, name := range names {
+			// A capability with a - prefix was disabled.
+			if strings.HasPrefix(name, "-") {
+				delete(rd.capabilities, name[1:])
+				continue
+			}
+
+			rd.capabilities[name] = true
+		}
+
+	case "NAK":
+		required := c.config.requiredCapabilities()
+
+		var rejected []string
+		for _, name := range names {
+			if required[name] {
+				rejected = append(rejected, name)
+			}
+		}
+
+		if len(rejected) > 0 {
+			return errors.Wrapf(ErrCapabilityRejected, "twitch rejected %s", strings.Join(rejected, ", "))
+		}
+	}
+
+	return nil
+}
+
+// awaitCapabilities reads the messages of the login until twitch replied to all capability requests.
+//
+// The messages are handled like in Run. It returns ErrCapabilityRejected if a required capability
+// was rejected and ErrAuthenticationFailed if twitch rejected the login.
+func (c *Connection) awaitCapabilities() error {
+	c.conn.SetReadDeadline(time.Now().Add(capabilityTimeout))
+	defer c.conn.SetReadDeadline(time.Time{})
+
+	requests := len(c.config.capabilityRequests())
+
+	for c.r.Scan() {
+		if err := c.handleLine(c.r.Text()); err != nil {
+			return errors.Wrap(err, "connection.awaitCapabilities: could not handle message")
+		}
+
+		if err := c.loginError(); err != nil {
+			return errors.Wrap(err, "connection.awaitCapabilities: could not log in")
+		}
+
+		c.readiness.lock.Lock()
+		replies := c.readiness.capReplies
+		c.readiness.lock.Unlock()
+
+		if replies >= requests {
+			return nil
+		}
+	}
+
+	if err := c.loginError(); err != nil {
+		return errors.Wrap(err, "connection.awaitCapabilities: could not log in")
+	}
+
+	if err := c.r.Err(); err != nil {
+		return errors.Wrap(err, "connection.awaitCapabilities: capabilities were not confirmed")
+	}
+
+	return errors.Wrap(ErrConnectionClosed, "connection.awaitCapabilities: connection was closed before the capabilities were confirmed")
+}
+
+// Capabilities returns the capabilities which were acknowledged by the server, sorted by name.
+func (c *Connection) Capabilities() []string {
+	c.readiness.lock.Lock()
+	defer c.readiness.lock.Unlock()
+
+	capabilities := make([]string, 0, len(c.readiness.capabilities))
+	for name := range c.readiness.capabilities {
+		capabilities = append(capabilities, name)
+	}
+
+	sort.Strings(capabilities)
+
+	return capabilities
+}
@@ -0,0 +1,111 @@
+package twitchirc
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestConnection_Capabilities(t *testing.T) {
+	t.Run("optional-rejected", func(t *testing.T) {
+		conn := &Connection{
+			config: &Config{CaptureTags: true, Capabilities: []Capability{
+				{Name: "twitch.tv/future"},
+			}},
+			handlerLock: &sync.RWMutex{},
+			ircHandler:  &IRCHandler{},
+		}
+
+		lines := []string{
+			":tmi.twitch.tv 001 julezdev :Welcome, GLHF!",
+			":tmi.twitch.tv CAP * ACK :twitch.tv/tags",
+			":tmi.twitch.tv CAP * NAK :twitch.tv/future",
+		}
+
+		for _, line := range lines {
+			if err := conn.handleLine(line); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		want := []string{"twitch.tv/tags"}
+		if got := conn.Capabilities(); !reflect.DeepEqual(got, want) {
+			t.Errorf("Capabilities() = %v, want %v", got, want)
+		}
+
+		select {
+		case <-conn.readiness.ready:
+		default:
+			t.Error("connection is not ready after all CAP REQ were answered")
+		}
+	})
+
+	t.Run("required-rejected", func(t *testing.T) {
+		conn := &Connection{
+			config: &Config{Capabilities: []Capability{
+				{Name: "twitch.tv/future", Required: true},
+			}},
+			handlerLock: &sync.RWMutex{},
+			ircHandler:  &IRCHandler{},
+		}
+
+		err := conn.handleLine(":tmi.twitch.tv CAP * NAK :twitch.tv/future")
+		if errors.Cause(err) != ErrCapabilityRejected {
+			t.Errorf("handleLine() error = %v, want %v", err, ErrCapabilityRejected)
+		}
+
+		if errors.Cause(conn.loginError()) != ErrCapabilityRejected {
+			t.Errorf("loginError() = %v, want %v", conn.loginError(), ErrCapabilityRejected)
+		}
+	})
+}
+
+func TestClient_Connect_RequiredCapability(t *testing.T) {
+	config := &Config{CaptureTags: true, Capabilities: []Capability{
+		{Name: "twitch.tv/future", Required: true},
+	}}
+
+	t.Run("acknowledged", func(t *testing.T) {
+		srv := newFakeServer()
+
+		client := NewAnonymousClient(config)
+		client.dialer = srv.dial
+
+		go func() {
+			fmt.Fprint(srv.accept(t), ":tmi.twitch.tv CAP * ACK :twitch.tv/tags twitch.tv/future\r\n")
+		}()
+
+		conn, err := client.Connect(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		if got, want := conn.Capabilities(), []string{"twitch.tv/future", "twitch.tv/tags"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("Capabilities() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		srv := newFakeServer()
+
+		client := NewAnonymousClient(config)
+		client.dialer = srv.dial
+
+		go func() {
+			fmt.Fprint(srv.accept(t), ":tmi.twitch.tv CAP * NAK :twitch.tv/tags twitch.tv/future\r\n")
+		}()
+
+		conn, err := client.Connect(nil)
+		if errors.Cause(err) != ErrCapabilityRejected {
+			t.Errorf("Connect() error = %v, want %v", err, ErrCapabilityRejected)
+		}
+
+		if conn != nil {
+			t.Errorf("Connect() = %v, want nil", conn)
+		}
+	})
+}
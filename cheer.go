@@ -0,0 +1,93 @@
+package twitchirc
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultCheermotePrefixes are the prefixes of the global cheermotes of twitch.
+//
+// Channels can have their own cheermotes, use ParseCheermotes with their prefixes to find them.
+var DefaultCheermotePrefixes = []string{
+	"Cheer", "DoodleCheer", "BibleThump", "cheerwhal", "Corgo", "Scoops", "uni", "ShowLove",
+	"Party", "SeemsGood", "Pride", "Kappa", "FrankerZ", "HeyGuys", "DansGame", "EleGiggle",
+	"TriHard", "Kreygasm", "4Head", "SwiftRage", "NotLikeThis", "FailFish", "VoHiYo", "PJSalt",
+	"MrDestructoid", "bday", "RIPCheer", "Shamrock", "BitBoss", "Streamlabs", "Muxy",
+	"HolidayCheer", "Goal", "Anon", "Charity",
+}
+
+// Cheermote is a cheer in the text of a PRIVMSG, like Cheer100.
+type Cheermote struct {
+	// Prefix is the name of the cheermote as it was written in the message.
+	Prefix string
+
+	// Amount is the number of bits cheered with the cheermote.
+	Amount int
+
+	// Start and End are the positions of the first and the last rune of the cheermote in the text.
+	Start int
+	End   int
+}
+
+// ParseCheermotes returns the cheermotes in text which start with one of the prefixes.
+//
+// A cheermote is a word made of a prefix followed by the amount, the prefixes are case insensitive.
+func ParseCheermotes(text string, prefixes []string) []*Cheermote {
+	var cheermotes []*Cheermote
+
+	position := 0
+
+	for _, word := range strings.Split(text, " ") {
+		length := utf8.RuneCountInString(word)
+
+		if cheermote := parseCheermote(word, prefixes); cheermote != nil {
+			cheermote.Start = position
+			cheermote.End = position + length - 1
+			cheermotes = append(cheermotes, cheermote)
+		}
+
+		position += length + 1
+	}
+
+	return cheermotes
+}
+
+// parseCheermote returns the cheermote of word or nil if word is not a cheermote.
+func parseCheermote(word string, prefixes []string) *Cheermote {
+	name := strings.TrimRight(word, "0123456789")
+
+	if name == word || name == "" {
+		return nil
+	}
+
+	amount, err := strconv.Atoi(word[len(name):])
+	if err != nil || amount <= 0 {
+		return nil
+	}
+
+	for _, prefix := range prefixes {
+		if strings.EqualFold(prefix, name) {
+			return &Cheermote{Prefix: name, Amount: amount}
+		}
+	}
+
+	return nil
+}
+
+// BitsBadgeTierMessage represents a parsed bitsbadgetier USERNOTICE.
+//
+// It is sent if a user unlocked a new tier of the bits badge.
+type BitsBadgeTierMessage struct {
+	*UserNoticeMessage
+
+	// Threshold is the number of bits of the unlocked tier.
+	Threshold int
+}
+
+func parseBitsBadgeTier(userNotice *UserNoticeMessage) *BitsBadgeTierMessage {
+	return &BitsBadgeTierMessage{
+		UserNoticeMessage: userNotice,
+		Threshold:         parseInt(userNotice.Raw, "msg-param-threshold"),
+	}
+}
@@ -0,0 +1,57 @@
+package twitchirc
+
+import (
+	"reflect"
+	"testing"
+)
+
+const (
+	cheer         = "@badge-info=;badges=bits/100;bits=150;color=;display-name=julezdev;emotes=;id=9f0c2c4e;mod=0;room-id=23161357;subscriber=0;tmi-sent-ts=1591719487292;user-id=530594933;user-type= :julezdev!julezdev@julezdev.tmi.twitch.tv PRIVMSG #lirik :Cheer100 großartig kappa50 Cheer10x"
+	bitsBadgeTier = `@badge-info=;badges=bits/1000;color=;display-name=julezdev;emotes=;flags=;id=7d1a;login=julezdev;mod=0;msg-id=bitsbadgetier;msg-param-threshold=1000;room-id=23161357;subscriber=0;system-msg=bits\sbadge\stier\snotification;tmi-sent-ts=1591719487292;user-id=530594933;user-type= :tmi.twitch.tv USERNOTICE #lirik :nice`
+)
+
+func TestParseCheermotes(t *testing.T) {
+	want := []*Cheermote{
+		{Prefix: "Cheer", Amount: 100, Start: 0, End: 7},
+		{Prefix: "kappa", Amount: 50, Start: 19, End: 25},
+	}
+
+	got := ParseCheermotes("Cheer100 großartig kappa50 Cheer10x Hello5", DefaultCheermotePrefixes)
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseCheermotes() = %v, want %v", got, want)
+	}
+}
+
+func TestChannelHandler_cheer(t *testing.T) {
+	var cheers []*PrivateMessage
+	var tiers []*BitsBadgeTierMessage
+
+	handler := &ChannelHandler{
+		OnCheer:         func(_ *Connection, m *PrivateMessage) { cheers = append(cheers, m) },
+		OnBitsBadgeTier: func(_ *Connection, m *BitsBadgeTierMessage) { tiers = append(tiers, m) },
+	}
+
+	for _, line := range []string{cheer, privEmote, bitsBadgeTier} {
+		if err := handler.HandleIRC(nil, mustParseMessage(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(cheers) != 1 || cheers[0].Bits != 150 || len(cheers[0].Cheermotes) != 2 {
+		t.Fatalf("OnCheer() got %v, want one cheer with two cheermotes", cheers)
+	}
+
+	total := 0
+	for _, cheermote := range cheers[0].Cheermotes {
+		total += cheermote.Amount
+	}
+
+	if total != cheers[0].Bits {
+		t.Errorf("cheermotes sum up to %d bits, want %d", total, cheers[0].Bits)
+	}
+
+	if len(tiers) != 1 || tiers[0].Threshold != 1000 || tiers[0].Text != "nice" {
+		t.Errorf("OnBitsBadgeTier() got %v, want the 1000 bits tier", tiers)
+	}
+}
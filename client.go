@@ -32,6 +32,29 @@ type Config struct {
 	CaptureTags       bool
 	CaptureCommands   bool
 	CaptureMembership bool
+
+	// Capabilities are requested in addition to the Capture flags.
+	// They can include capabilities which are unknown to this package.
+	Capabilities []Capability
+
+	// Reconnect enables automatic reconnects after the connection to the server was lost.
+	// Reconnecting is disabled if Reconnect is nil.
+	Reconnect *ReconnectConfig
+
+	// RateLimit limits the chat messages of all connections of the client.
+	// Messages are not limited if RateLimit is nil.
+	RateLimit *RateLimit
+
+	// RateLimitMode decides what happens to a message which exceeds the RateLimit.
+	RateLimitMode RateLimitMode
+
+	// JoinLimit limits the channels joined by all connections of the client.
+	// DefaultJoinLimit is used if JoinLimit is nil.
+	JoinLimit *JoinLimit
+
+	// ParseMode decides if malformed fields are skipped or drop their whole message.
+	// The default ParseLenient skips them.
+	ParseMode ParseMode
 }
 
 // Client holds a client which allows creating connections to the twitch irc servers
@@ -39,6 +62,15 @@ type Client struct {
 	nick   string
 	pass   string
 	config *Config
+
+	// dialer replaces the network dial, it is only set in tests.
+	dialer func() (net.Conn, error)
+
+	limiterOnce sync.Once
+	limiter     *rateLimiter
+
+	joinLimiterOnce sync.Once
+	joins           *joinLimiter
 }
 
 // NewClient returns a new client with the provided config
@@ -75,6 +107,9 @@ func NewAnonymousClient(conf *Config) *Client {
 // Use the connections JoinChannels method to join a channel with a provided handler struct
 // if you want to handle room specific events.
 //
+// If Config.Capabilities contains a required capability, Connect waits until twitch replied
+// to the capability requests and returns ErrCapabilityRejected if a required capability was rejected.
+//
 // This method creates a net.Conn which could leak if the returned connection
 // does not get a chance to close the connection.
 func (c *Client) Connect(ircHandler Handler) (*Connection, error) {
@@ -83,49 +118,103 @@ func (c *Client) Connect(ircHandler Handler) (*Connection, error) {
 		ircHandler = &IRCHandler{}
 	}
 
-	dialer := &net.Dialer{
-		KeepAlive: time.Second * 10,
+	conn, r, w, err := c.open()
+
+	if err != nil {
+		return nil, errors.Wrap(err, "client.Connect: could not open connection")
 	}
 
-	var (
-		conn net.Conn
-		err  error
-	)
+	connection := &Connection{
+		channelHandler: make(map[string]Handler),
+		ircHandler:     ircHandler,
+		handlerLock:    &sync.RWMutex{},
+		config:         c.config,
+		client:         c,
+		conn:           conn,
+		r:              r,
+		w:              w,
+	}
 
-	if c.config.UseTLS {
-		conn, err = tls.DialWithDialer(dialer, "tcp", chatTLS, &tls.Config{})
-	} else {
-		ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
-		defer cancel()
-		conn, err = dialer.DialContext(ctx, "tcp", chatNoneTLS)
+	if len(c.config.requiredCapabilities()) > 0 {
+		if err := connection.awaitCapabilities(); err != nil {
+			connection.Close()
+			return nil, errors.Wrap(err, "client.Connect: could not negotiate capabilities")
+		}
 	}
 
+	return connection, nil
+}
+
+// open dials the twitch IRC servers and sends the authentication and capture messages.
+func (c *Client) open() (net.Conn, *bufio.Scanner, *bufio.Writer, error) {
+	conn, err := c.dial()
+
 	if err != nil {
-		return nil, errors.Wrap(err, "client.Connect: could not dial twitch server")
+		return nil, nil, nil, errors.Wrap(err, "client.open: could not dial twitch server")
 	}
 
 	r := bufio.NewScanner(conn)
 	w := bufio.NewWriter(conn)
 
 	if err = c.sendAuth(w); err != nil {
-		return nil, errors.Wrap(err, "client.Connect: could not send authentication")
+		conn.Close()
+		return nil, nil, nil, errors.Wrap(err, "client.open: could not send authentication")
 	}
 
 	if err = c.sendCaptures(w); err != nil {
-		return nil, errors.Wrap(err, "connection.Connect: could not send irc captures")
+		conn.Close()
+		return nil, nil, nil, errors.Wrap(err, "client.open: could not send irc captures")
 	}
 
-	connection := &Connection{
-		channelHandler: make(map[string]Handler),
-		ircHandler:     ircHandler,
-		handlerLock:    &sync.RWMutex{},
-		config:         c.config,
-		conn:           conn,
-		r:              r,
-		w:              w,
+	if err = w.Flush(); err != nil {
+		conn.Close()
+		return nil, nil, nil, errors.Wrap(err, "client.open: could not flush buffer")
 	}
 
-	return connection, nil
+	return conn, r, w, nil
+}
+
+// rateLimiter returns the rate limiter shared by all connections of the client
+// or nil if Config.RateLimit is not set.
+func (c *Client) rateLimiter() *rateLimiter {
+	if c.config.RateLimit == nil {
+		return nil
+	}
+
+	c.limiterOnce.Do(func() {
+		c.limiter = newRateLimiter(*c.config.RateLimit, c.config.RateLimitMode)
+	})
+
+	return c.limiter
+}
+
+// joinLimiter returns the join limiter shared by all connections of the client.
+func (c *Client) joinLimiter() *joinLimiter {
+	c.joinLimiterOnce.Do(func() {
+		c.joins = newJoinLimiter(c.config.JoinLimit)
+	})
+
+	return c.joins
+}
+
+// dial creates a new net.Conn to the twitch IRC servers.
+func (c *Client) dial() (net.Conn, error) {
+	if c.dialer != nil {
+		return c.dialer()
+	}
+
+	dialer := &net.Dialer{
+		KeepAlive: time.Second * 10,
+	}
+
+	if c.config.UseTLS {
+		return tls.DialWithDialer(dialer, "tcp", chatTLS, &tls.Config{})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	return dialer.DialContext(ctx, "tcp", chatNoneTLS)
 }
 
 // sendAuth sends the authentication messages into w
@@ -142,18 +231,7 @@ func (c *Client) sendAuth(w io.Writer) error {
 
 // sendCaptures sends the capture messages into w
 func (c *Client) sendCaptures(w io.Writer) error {
-	captures := []string{}
-	if c.config.CaptureTags {
-		captures = append(captures, "twitch.tv/tags")
-	}
-	if c.config.CaptureCommands {
-		captures = append(captures, "twitch.tv/commands")
-	}
-	if c.config.CaptureMembership {
-		captures = append(captures, "twitch.tv/membership")
-	}
-
-	if len(captures) > 0 {
+	for _, captures := range c.config.capabilityRequests() {
 		captureString := strings.Join(captures, " ")
 		captureString = fmt.Sprintf("CAP REQ :%s\r\n", captureString)
 
@@ -57,6 +57,15 @@ func TestClient_sendCaptures(t *testing.T) {
 			&Config{CaptureTags: true, CaptureCommands: true, CaptureMembership: true},
 			"CAP REQ :twitch.tv/tags twitch.tv/commands twitch.tv/membership\r\n",
 		},
+		{
+			"capabilities",
+			&Config{CaptureTags: true, Capabilities: []Capability{
+				{Name: "twitch.tv/commands", Required: true},
+				{Name: "twitch.tv/future"},
+				{Name: "twitch.tv/tags"},
+			}},
+			"CAP REQ :twitch.tv/tags twitch.tv/commands\r\nCAP REQ :twitch.tv/future\r\n",
+		},
 	}
 
 	for _, tt := range table {
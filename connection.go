@@ -7,30 +7,75 @@ import (
 	"net"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 )
 
+// ErrConnectionClosed is returned if a message could not be sent because the connection was closed.
+var ErrConnectionClosed = errors.New("twitchirc: connection was closed")
+
 // Connection represents a connection to the twitch IRC server.
 //
 // It holds the active connection created by the Client.Connect() method.
 // Additionally its holds all chat handlers.
 type Connection struct {
 	config *Config
+	client *Client
 
 	handlerLock    *sync.RWMutex
 	channelHandler map[string]Handler
 	ircHandler     Handler
 
+	// connLock guards conn, w, r and the closed state, which get replaced on reconnects.
+	connLock  sync.Mutex
+	writeLock sync.Mutex
+	closed    bool
+	done      chan struct{}
+	handover  chan struct{}
+
+	// dedupe drops messages which are received on both connections while a RECONNECT is handled.
+	dedupe dedupe
+
+	// readiness tracks whether twitch confirmed the login.
+	readiness readiness
+
+	joins            joinQueue
+	localJoinLimiter *joinLimiter
+
+	// queueErr is the error of a queued message which could not be sent, guarded by connLock.
+	queueErr *QueueError
+
+	// stateLock guards the state twitch reports about the user in the joined channels.
+	stateLock  sync.RWMutex
+	self       *UserState
+	userStates map[string]*UserState
+	rooms      map[string]*RoomStateChange
+	presences  map[string]*presence
+
 	conn net.Conn
 	w    *bufio.Writer
 	r    *bufio.Scanner
+
+	// previousR and previousW belong to the connection replaced by a RECONNECT while it is still read.
+	previousR *bufio.Scanner
+	previousW *bufio.Writer
+}
+
+// readResult is a line read from src or the end of src if done is set.
+type readResult struct {
+	src  *bufio.Scanner
+	line string
+	done bool
+	err  error
 }
 
 // Run parses the messages from the connection.
 //
 // Run blocks the current goroutine until ctx is canceled
 // or the bufio.Scanner.Scan method returns false.
+// If Config.Reconnect is set, Run reconnects instead of returning when the scanner stops
+// and only returns if ctx is canceled, the connection gets closed or all reconnect attempts failed.
 //
 // If this method is cancled it will automatically close the connection.
 //
@@ -38,65 +83,180 @@ type Connection struct {
 // if a provided handler will take too long to proccess.
 //
 // The reader will wait until the last message was parsed.
+//
+// If twitch sends a RECONNECT, Run opens a new connection and joins all channels again
+// before the old connection gets closed. Messages which are received on both connections
+// are only passed to the handlers once.
 func (c *Connection) Run(ctx context.Context) error {
-	errCh := make(chan error)
-
 	ctx, cancel := context.WithCancel(ctx)
 
+	// wg tracks the RECONNECT handling so it ends before Run returns.
+	wg := &sync.WaitGroup{}
+
 	defer func() {
+		cancel()
 		c.Close()
-		close(errCh)
+		wg.Wait()
 	}()
 
-	go func() {
-		defer cancel()
+	c.connLock.Lock()
+	handover := c.handoverChan()
+	c.connLock.Unlock()
+
+	results := make(chan readResult)
+	go c.read(ctx, c.reader(), results)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-handover:
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				c.handleReconnect(ctx, results)
+			}()
+		case res := <-results:
+			if !res.done {
+				if err := c.handleLineFrom(res.src, res.line); err != nil {
+					return errors.Wrap(err, "connection.Run: could not handle message")
+				}
+
+				continue
+			}
+
+			// The old connection of a RECONNECT was closed, so the messages can't be received twice anymore.
+			if res.src != c.reader() {
+				c.dedupe.stop()
+				c.forgetPrevious(res.src)
+				continue
+			}
 
-		for c.r.Scan() {
-			if err := c.handleLine(c.r.Text()); err != nil {
-				errCh <- errors.Wrap(err, "connection.Run: could not handle message")
-				return
+			// Reconnecting would fail again if twitch rejected the login.
+			if err := c.loginError(); err != nil {
+				return errors.Wrap(err, "connection.Run: could not log in")
 			}
+
+			if c.config.Reconnect == nil || ctx.Err() != nil || c.isClosed() {
+				return nil
+			}
+
+			c.emitState(&StateChange{State: StateDisconnected, Err: res.err})
+
+			r, err := c.reconnect(ctx)
+
+			if err != nil {
+				return errors.Wrap(err, "connection.Run: could not reconnect")
+			}
+
+			if r == nil {
+				return nil
+			}
+
+			go c.read(ctx, r, results)
 		}
-	}()
+	}
+}
+
+// read sends every line of r into results until r stops or ctx is canceled.
+func (c *Connection) read(ctx context.Context, r *bufio.Scanner, results chan<- readResult) {
+	for r.Scan() {
+		select {
+		case results <- readResult{src: r, line: r.Text()}:
+		case <-ctx.Done():
+			return
+		}
+	}
 
 	select {
+	case results <- readResult{src: r, done: true, err: r.Err()}:
 	case <-ctx.Done():
-		return nil
-	case err := <-errCh:
-		return err
 	}
 }
 
+// reader returns the scanner of the current underlying connection.
+func (c *Connection) reader() *bufio.Scanner {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+
+	return c.r
+}
+
 // handleLine sends a parsed message to the ircHandler or the chatHandler for the channel.
 func (c *Connection) handleLine(line string) error {
-	msg, err := parseMessage(line)
+	return c.handleLineFrom(nil, line)
+}
+
+// handleLineFrom is the same as handleLine for a line read from src.
+// While a RECONNECT is handled, messages which were already received on the other connection are dropped.
+func (c *Connection) handleLineFrom(src *bufio.Scanner, line string) error {
+	msg, err := ParseMessage(line)
 
 	if err != nil {
-		return errors.Wrap(err, "connection.handleLine: could not parse message")
+		return c.parseFailure(errors.Wrap(err, "connection.handleLine: could not parse message"))
+	}
+
+	if c.config.ParseMode == ParseStrict {
+		if err := ValidateMessage(msg); err != nil {
+			return c.parseFailure(errors.Wrap(err, "connection.handleLine: invalid message"))
+		}
+	}
+
+	// Every connection gets its own control messages, like PING, so they are never duplicates.
+	if !isControlCommand(msg.Command) && c.dedupe.duplicate(src, msg) {
+		return nil
 	}
 
 	if c.config.AutoPing && msg.Command == "PING" {
-		if err := c.sendPong(); err != nil {
+		if err := c.sendPong(src); err != nil {
 			return err
 		}
 	}
 
-	stream := strings.TrimPrefix(msg.Params[0], "#")
+	if msg.Command == "RECONNECT" {
+		c.requestHandover()
+	}
+
+	stream := messageChannel(msg)
+
+	switch msg.Command {
+	case "001", "CAP":
+		if err := c.updateReadiness(msg); err != nil {
+			return errors.Wrap(err, "connection.handleLine: could not log in")
+		}
+	case "GLOBALUSERSTATE":
+		c.updateUserState(msg)
+	case "USERSTATE":
+		c.updateUserState(msg)
+		c.confirmJoin(stream)
+	case "ROOMSTATE":
+		c.updateRoomState(stream, msg)
+		c.confirmJoin(stream)
+	case "NOTICE":
+		if err := c.updateReadiness(msg); err != nil {
+			return errors.Wrap(err, "connection.handleLine: could not log in")
+		}
+
+		c.rejectJoin(stream, msg)
+	case "JOIN", "PART", "353", "366":
+		c.updatePresence(stream, msg)
+	}
 
-	// The stream is tmi.twitch.tv if its about the IRC connection itself.
+	// The stream is tmi.twitch.tv, * or empty if its about the IRC connection itself.
 	// So we will let the ircHandler worry about that and return early.
-	if stream == "tmi.twitch.tv" || msg.Command == "WHISPER" {
+	if stream == "tmi.twitch.tv" || stream == "*" || stream == "" || msg.Command == "WHISPER" {
 		if err = c.ircHandler.HandleIRC(c, msg); err != nil {
-			return errors.Wrap(err, "connection.handleLine: could not handle message with the provided irc handler")
+			return c.parseFailure(errors.Wrap(err, "connection.handleLine: could not handle message with the provided irc handler"))
 		}
 	}
 
 	c.handlerLock.RLock()
-	defer c.handlerLock.RUnlock()
+	chatHandler, ok := c.channelHandler[stream]
+	c.handlerLock.RUnlock()
 
-	if chatHandler, ok := c.channelHandler[stream]; ok {
+	if ok {
 		if err = chatHandler.HandleIRC(c, msg); err != nil {
-			return errors.Wrap(err, "connection.handleLine: could not handle message with the provided chat handler")
+			return c.parseFailure(errors.Wrap(err, "connection.handleLine: could not handle message with the provided chat handler"))
 		}
 	}
 
@@ -107,26 +267,37 @@ func (c *Connection) handleLine(line string) error {
 // If the handler is nil an empty twitchirc.ChannelHandler will be used
 //
 // If the channel already had a handler it will not be overwritten.
+//
+// The channels are not joined immediately. They get queued and are sent in batched
+// JOIN messages which respect Config.JoinLimit, so Join never blocks the message handling.
+// If the irc handler implements JoinHandler it gets notified about the progress
+// and about channels which could not be joined.
 func (c *Connection) Join(channels []string, handler Handler) error {
 	if handler == nil {
 		handler = &ChannelHandler{}
 	}
 
+	if c.isClosed() {
+		return errors.Wrapf(ErrConnectionClosed, "connection.Join: could not join channels %s", channels)
+	}
+
+	added := make([]string, 0, len(channels))
+
 	c.handlerLock.Lock()
-	defer c.handlerLock.Unlock()
 
 	for _, ch := range channels {
 		ch = strings.ToLower(ch)
 
 		if _, ok := c.channelHandler[ch]; !ok {
 			c.channelHandler[ch] = handler
-			if err := c.Write(fmt.Sprintf("JOIN #%s", ch)); err != nil {
-				return errors.Wrapf(err, "connection.Join: could not join channel %s", channels)
-			}
+			added = append(added, ch)
 		}
-
 	}
 
+	c.handlerLock.Unlock()
+
+	c.scheduleJoin(added)
+
 	return nil
 }
 
@@ -139,6 +310,8 @@ func (c *Connection) JoinOne(channel string, handler Handler) error {
 func (c *Connection) Depart(channel string) error {
 	channel = strings.ToLower(channel)
 
+	c.unscheduleJoin(channel)
+
 	if err := c.Write(fmt.Sprintf("PART #%s", channel)); err != nil {
 		return errors.Wrapf(err, "connection.Depart could not depart %s", channel)
 	}
@@ -147,12 +320,16 @@ func (c *Connection) Depart(channel string) error {
 	delete(c.channelHandler, channel)
 	c.handlerLock.Unlock()
 
+	c.forgetRoomState(channel)
+	c.forgetUserState(channel)
+	c.forgetPresence(channel)
+
 	return nil
 }
 
 // DepartAll calls Depart for all channels in the channel handler
 func (c *Connection) DepartAll() error {
-	for v := range c.channelHandler {
+	for _, v := range c.channels() {
 		if err := c.Depart(v); err != nil {
 			return err
 		}
@@ -161,14 +338,110 @@ func (c *Connection) DepartAll() error {
 	return nil
 }
 
+// channels returns all channels in the channel handler.
+func (c *Connection) channels() []string {
+	c.handlerLock.RLock()
+	defer c.handlerLock.RUnlock()
+
+	channels := make([]string, 0, len(c.channelHandler))
+	for ch := range c.channelHandler {
+		channels = append(channels, ch)
+	}
+
+	return channels
+}
+
 // Close closes the connection.
 //
-// This means the underlying net.Conn gets closed.
+// This means the underlying net.Conn gets closed and the connection
+// will not reconnect anymore, even if Config.Reconnect is set.
 // So you need to create a new connection if you want to reconnect.
 func (c *Connection) Close() error {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+
+	if !c.closed {
+		c.closed = true
+		close(c.doneChan())
+	}
+
 	return c.conn.Close()
 }
 
+// isClosed reports whether Close was called.
+func (c *Connection) isClosed() bool {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+
+	return c.closed
+}
+
+// handoverChan returns the channel which signals Run that twitch requested a reconnect.
+// The caller must hold connLock.
+func (c *Connection) handoverChan() chan struct{} {
+	if c.handover == nil {
+		c.handover = make(chan struct{}, 1)
+	}
+
+	return c.handover
+}
+
+// requestHandover tells Run to replace the connection.
+// It does nothing if the connection was not created by a client.
+func (c *Connection) requestHandover() {
+	if c.client == nil {
+		return
+	}
+
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+
+	select {
+	case c.handoverChan() <- struct{}{}:
+	default:
+	}
+}
+
+// closedSignal returns a channel which gets closed by Close.
+func (c *Connection) closedSignal() <-chan struct{} {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+
+	return c.doneChan()
+}
+
+// waitReserved calls reserve until it returns zero and sleeps for the returned time in between.
+// It returns ErrConnectionClosed if the connection gets closed while waiting.
+func (c *Connection) waitReserved(reserve func() time.Duration) error {
+	closed := c.closedSignal()
+
+	for {
+		wait := reserve()
+		if wait == 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(wait)
+
+		select {
+		case <-timer.C:
+		case <-closed:
+			timer.Stop()
+			return ErrConnectionClosed
+		}
+	}
+}
+
+// doneChan returns the channel which gets closed by Close.
+// The caller must hold connLock.
+func (c *Connection) doneChan() chan struct{} {
+	if c.done == nil {
+		c.done = make(chan struct{})
+	}
+
+	return c.done
+}
+
 // Say is a wrapper over Write() which allows saying PRIVMSG in the provided channel.
 func (c *Connection) Say(channel, text string) error {
 	channel = strings.ToLower(channel)
@@ -176,7 +449,17 @@ func (c *Connection) Say(channel, text string) error {
 }
 
 // Write writes message into the connection.
+//
+// If Config.RateLimit is set, PRIVMSG messages are limited by the rate limiter of the client.
+// Depending on Config.RateLimitMode Write blocks, queues the message or returns a *RateLimitError
+// if the message exceeds the limit.
 func (c *Connection) Write(message string) error {
+	if l := c.rateLimiter(); l != nil {
+		if channel, ok := privateMessageChannel(message); ok {
+			return l.send(c, channel, message, c.isModerator(channel))
+		}
+	}
+
 	_, err := c.write(message)
 
 	if err != nil {
@@ -188,13 +471,25 @@ func (c *Connection) Write(message string) error {
 
 // write writes message into the connection and flushes the buffer.
 func (c *Connection) write(message string) (int, error) {
-	n, err := c.w.WriteString(fmt.Sprintf("%s\r\n", message))
+	c.connLock.Lock()
+	w := c.w
+	c.connLock.Unlock()
+
+	return c.writeTo(w, message)
+}
+
+// writeTo writes message into w and flushes the buffer.
+func (c *Connection) writeTo(w *bufio.Writer, message string) (int, error) {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	n, err := w.WriteString(fmt.Sprintf("%s\r\n", message))
 
 	if err != nil {
 		return 0, errors.Wrapf(err, "connection.write: could not write message %s", message)
 	}
 
-	err = c.w.Flush()
+	err = w.Flush()
 
 	if err != nil {
 		return 0, errors.Wrap(err, "connection.write: could not flush buffer")
@@ -203,7 +498,51 @@ func (c *Connection) write(message string) (int, error) {
 	return n, nil
 }
 
-// sendPong sends a Pong response
-func (c *Connection) sendPong() error {
-	return c.Write("PONG :tmi.twitch.tv")
+// rateLimiter returns the rate limiter of the client or nil if messages are not limited.
+func (c *Connection) rateLimiter() *rateLimiter {
+	if c.client == nil {
+		return nil
+	}
+
+	return c.client.rateLimiter()
+}
+
+// sendPong sends a Pong response on the connection src was read from.
+func (c *Connection) sendPong(src *bufio.Scanner) error {
+	_, err := c.writeTo(c.writer(src), "PONG :tmi.twitch.tv")
+
+	return err
+}
+
+// writer returns the writer of the connection src was read from.
+// The current writer is returned if src is nil or not known anymore.
+func (c *Connection) writer(src *bufio.Scanner) *bufio.Writer {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+
+	if src != nil && src == c.previousR {
+		return c.previousW
+	}
+
+	return c.w
+}
+
+// forgetPrevious forgets the connection replaced by a RECONNECT once r was read completely.
+func (c *Connection) forgetPrevious(r *bufio.Scanner) {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+
+	if c.previousR == r {
+		c.previousR, c.previousW = nil, nil
+	}
+}
+
+// isControlCommand reports whether command is about a single underlying connection.
+func isControlCommand(command string) bool {
+	switch command {
+	case "PING", "PONG", "RECONNECT", "CAP":
+		return true
+	}
+
+	return isNumeric(command)
 }
@@ -7,6 +7,7 @@ import (
 	"net"
 	"sync"
 	"testing"
+	"time"
 )
 
 const (
@@ -26,6 +27,99 @@ func (t *testHandler) HandleIRC(c *Connection, m *Message) error {
 	return nil
 }
 
+// fakeServer creates in-memory connections for a client and collects all lines the client writes.
+type fakeServer struct {
+	conns chan net.Conn
+	lines chan string
+
+	// connLines holds the lines written into every connection, by the server side of the connection.
+	lock      sync.Mutex
+	connLines map[net.Conn]chan string
+}
+
+func newFakeServer() *fakeServer {
+	return &fakeServer{
+		conns:     make(chan net.Conn, 10),
+		lines:     make(chan string, 1000),
+		connLines: make(map[net.Conn]chan string),
+	}
+}
+
+func (s *fakeServer) dial() (net.Conn, error) {
+	server, client := net.Pipe()
+
+	lines := make(chan string, 1000)
+
+	s.lock.Lock()
+	s.connLines[server] = lines
+	s.lock.Unlock()
+
+	go func() {
+		r := bufio.NewScanner(server)
+		for r.Scan() {
+			s.lines <- r.Text()
+			lines <- r.Text()
+		}
+	}()
+
+	s.conns <- server
+
+	return client, nil
+}
+
+// accept returns the server side of the next connection.
+func (s *fakeServer) accept(t *testing.T) net.Conn {
+	t.Helper()
+
+	select {
+	case conn := <-s.conns:
+		return conn
+	case <-time.After(time.Second):
+		t.Fatal("no connection was created")
+		return nil
+	}
+}
+
+// expect reads the written lines until want was found.
+func (s *fakeServer) expect(t *testing.T, want string) {
+	t.Helper()
+
+	timeout := time.After(time.Second)
+
+	for {
+		select {
+		case line := <-s.lines:
+			if line == want {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("line %q was not written", want)
+		}
+	}
+}
+
+// expectOn reads the lines written into the connection of server until want was found.
+func (s *fakeServer) expectOn(t *testing.T, server net.Conn, want string) {
+	t.Helper()
+
+	s.lock.Lock()
+	lines := s.connLines[server]
+	s.lock.Unlock()
+
+	timeout := time.After(time.Second)
+
+	for {
+		select {
+		case line := <-lines:
+			if line == want {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("line %q was not written into the connection", want)
+		}
+	}
+}
+
 // type testHandlerFail struct {
 // 	err error
 // }
@@ -153,3 +247,240 @@ func TestConnection_handleLine(t *testing.T) {
 		client.Close()
 	})
 }
+
+func TestConnection_Reconnect(t *testing.T) {
+	srv := newFakeServer()
+
+	states := make(chan ConnectionState, 10)
+	ircHandler := &IRCHandler{
+		OnDisconnect: func(*Connection, error) { states <- StateDisconnected },
+		OnReconnect:  func(*Connection) { states <- StateReconnected },
+	}
+
+	client := NewAnonymousClient(&Config{
+		Reconnect: &ReconnectConfig{MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond * 5},
+	})
+	client.dialer = srv.dial
+
+	conn, err := client.Connect(ircHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := srv.accept(t)
+
+	got := make(chan string, 1)
+	err = conn.JoinOne("julezdev", &ChannelHandler{
+		OnPrivateMessage: func(_ *Connection, m *PrivateMessage) { got <- m.Text },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv.expect(t, "JOIN #julezdev")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+
+	go func() {
+		errCh <- conn.Run(ctx)
+	}()
+
+	first.Close()
+
+	second := srv.accept(t)
+	srv.expect(t, "PASS "+anonymousPass)
+	srv.expect(t, "JOIN #julezdev")
+
+	fmt.Fprintln(second, privMSG)
+
+	select {
+	case text := <-got:
+		if text != "test" {
+			t.Errorf("OnPrivateMessage() = %v, want %v", text, "test")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("message after reconnect was not handled")
+	}
+
+	if s := <-states; s != StateDisconnected {
+		t.Errorf("first state = %v, want %v", s, StateDisconnected)
+	}
+
+	if s := <-states; s != StateReconnected {
+		t.Errorf("second state = %v, want %v", s, StateReconnected)
+	}
+
+	cancel()
+
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReconnectConfig_backoff(t *testing.T) {
+	conf := &ReconnectConfig{MinBackoff: time.Second, MaxBackoff: time.Second * 10}
+
+	table := []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{1, time.Second},
+		{2, time.Second * 2},
+		{3, time.Second * 4},
+		{4, time.Second * 8},
+		{5, time.Second * 10},
+		{50, time.Second * 10},
+	}
+
+	for _, tt := range table {
+		got := conf.backoff(tt.attempt)
+
+		if got < tt.max/2 || got > tt.max {
+			t.Errorf("backoff(%d) = %v, want between %v and %v", tt.attempt, got, tt.max/2, tt.max)
+		}
+	}
+}
+
+func TestConnection_handleReconnect(t *testing.T) {
+	overlap := handoverOverlap
+	handoverOverlap = time.Millisecond * 50
+	defer func() { handoverOverlap = overlap }()
+
+	srv := newFakeServer()
+
+	client := NewAnonymousClient(&Config{})
+	client.dialer = srv.dial
+
+	conn, err := client.Connect(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := srv.accept(t)
+
+	got := make(chan string, 10)
+	err = conn.JoinOne("julezdev", &ChannelHandler{
+		OnPrivateMessage: func(_ *Connection, m *PrivateMessage) { got <- m.ID },
+		OnUserJoin:       func(_ *Connection, m *MembershipMessage) { got <- "join " + m.User },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv.expect(t, "JOIN #julezdev")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+
+	go func() {
+		errCh <- conn.Run(ctx)
+	}()
+
+	fmt.Fprintln(first, ":tmi.twitch.tv RECONNECT")
+
+	second := srv.accept(t)
+	srv.expect(t, "JOIN #julezdev")
+
+	// the same messages are received on both connections during the overlap
+	join := ":ronni!ronni@ronni.tmi.twitch.tv JOIN #julezdev"
+
+	fmt.Fprintln(first, privMSG)
+	fmt.Fprintln(first, join)
+	fmt.Fprintln(second, privMSG)
+	fmt.Fprintln(second, join)
+
+	second.Write([]byte("@id=next :julezdev!julezdev@julezdev.tmi.twitch.tv PRIVMSG #julezdev :next\r\n"))
+
+	want := []string{"5bb550d4-bd15-4a96-9de2-c0298b2d01a9", "join ronni", "next"}
+	for _, w := range want {
+		select {
+		case id := <-got:
+			if id != w {
+				t.Errorf("OnPrivateMessage() id = %v, want %v", id, w)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("message %v was not handled", w)
+		}
+	}
+
+	cancel()
+
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConnection_handleReconnect_ping(t *testing.T) {
+	overlap := handoverOverlap
+	handoverOverlap = time.Second
+	defer func() { handoverOverlap = overlap }()
+
+	srv := newFakeServer()
+
+	client := NewAnonymousClient(&Config{AutoPing: true})
+	client.dialer = srv.dial
+
+	conn, err := client.Connect(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := srv.accept(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+
+	go func() {
+		errCh <- conn.Run(ctx)
+	}()
+
+	fmt.Fprintln(first, ":tmi.twitch.tv RECONNECT")
+
+	second := srv.accept(t)
+	srv.expectOn(t, second, "NICK justinfan123123")
+
+	// both connections get the same PING during the overlap and each needs its own PONG
+	fmt.Fprintln(second, "PING :tmi.twitch.tv")
+	fmt.Fprintln(first, "PING :tmi.twitch.tv")
+
+	srv.expectOn(t, second, "PONG :tmi.twitch.tv")
+	srv.expectOn(t, first, "PONG :tmi.twitch.tv")
+
+	cancel()
+
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDedupe(t *testing.T) {
+	var d dedupe
+
+	oldConn, newConn := &bufio.Scanner{}, &bufio.Scanner{}
+	join := mustParseMessage(":ronni!ronni@ronni.tmi.twitch.tv JOIN #julezdev")
+
+	if d.duplicate(oldConn, join) {
+		t.Error("inactive dedupe reported a duplicate")
+	}
+
+	d.start()
+
+	table := []struct {
+		src  *bufio.Scanner
+		want bool
+	}{
+		{oldConn, false},
+		// an equal line on the same connection is a new message
+		{oldConn, false},
+		{newConn, true},
+		{newConn, true},
+		{newConn, false},
+	}
+
+	for i, tt := range table {
+		if got := d.duplicate(tt.src, join); got != tt.want {
+			t.Errorf("duplicate() #%d = %v, want %v", i, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package twitchirc
+
+import (
+	"github.com/pkg/errors"
+)
+
+// RawMessage is returned by ParseEvent for messages without a specialized type.
+type RawMessage struct {
+	Command string
+
+	Raw *Message
+}
+
+// ParseEvent parses message into the specialized type of its command.
+//
+// The returned value is one of
+//
+//	*PingMessage           PING
+//	*PrivateMessage        PRIVMSG
+//	*WhisperMessage        WHISPER
+//	*ClearChatMessage      CLEARCHAT
+//	*ClearMessage          CLEARMSG
+//	*NoticeMessage         NOTICE
+//	*UserState             USERSTATE, GLOBALUSERSTATE
+//	*RoomState             ROOMSTATE
+//	*MembershipMessage     JOIN, PART
+//	*NamesMessage          353
+//
+// A USERNOTICE is parsed into a *SubscriptionMessage, *GiftSubMessage, *MysteryGiftMessage,
+// *GiftUpgradeMessage, *RaidEvent, *UnraidEvent or *BitsBadgeTierMessage depending on its msg-id,
+// other USERNOTICE messages are returned as *UserNoticeMessage.
+// All other commands are returned as *RawMessage.
+func ParseEvent(message *Message) (interface{}, error) {
+	var (
+		event interface{}
+		err   error
+	)
+
+	switch message.Command {
+	case "PING":
+		event = parsePing(message)
+	case "PRIVMSG":
+		event, err = ParsePrivateMessage(message)
+	case "WHISPER":
+		event, err = ParseWhisper(message)
+	case "CLEARCHAT":
+		event, err = ParseClearChat(message)
+	case "CLEARMSG":
+		event, err = ParseClearMessage(message)
+	case "NOTICE":
+		event, err = ParseNotice(message)
+	case "USERSTATE", "GLOBALUSERSTATE":
+		event, err = ParseUserState(message)
+	case "ROOMSTATE":
+		state := RoomState{}.merge(message)
+		event = &state
+	case "JOIN", "PART":
+		event, err = ParseMembership(message)
+	case "353":
+		event, err = ParseNames(message)
+	case "USERNOTICE":
+		var userNotice *UserNoticeMessage
+
+		userNotice, err = ParseUserNotice(message)
+		if err == nil {
+			event = userNoticeEvent(userNotice)
+		}
+	default:
+		event = &RawMessage{Command: message.Command, Raw: message}
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "twitchirc.ParseEvent: could not parse %s", message.Command)
+	}
+
+	return event, nil
+}
+
+// userNoticeEvent returns the specialized message for the msg-id of the USERNOTICE.
+func userNoticeEvent(userNotice *UserNoticeMessage) interface{} {
+	switch userNotice.MsgID {
+	case "sub", "resub":
+		return parseSubscription(userNotice)
+	case "subgift", "anonsubgift":
+		return parseGiftSub(userNotice)
+	case "submysterygift", "anonsubmysterygift":
+		return parseMysteryGift(userNotice)
+	case "giftpaidupgrade", "anongiftpaidupgrade":
+		return parseGiftUpgrade(userNotice)
+	case "raid":
+		return parseRaid(userNotice)
+	case "unraid":
+		return parseUnraid(userNotice)
+	case "bitsbadgetier":
+		return parseBitsBadgeTier(userNotice)
+	}
+
+	return userNotice
+}
@@ -0,0 +1,54 @@
+package twitchirc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseEvent(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want reflect.Type
+	}{
+		{"ping", "PING :tmi.twitch.tv", reflect.TypeOf(&PingMessage{})},
+		{"privmsg", privEmote, reflect.TypeOf(&PrivateMessage{})},
+		{"whisper", whisperEmote, reflect.TypeOf(&WhisperMessage{})},
+		{"clearchat", timeout, reflect.TypeOf(&ClearChatMessage{})},
+		{"clearmsg", clearMessage, reflect.TypeOf(&ClearMessage{})},
+		{"notice", slowModeNotice, reflect.TypeOf(&NoticeMessage{})},
+		{"userstate", userStateMod, reflect.TypeOf(&UserState{})},
+		{"roomstate", "@emote-only=0;room-id=530594933;slow=0 :tmi.twitch.tv ROOMSTATE #julezdev", reflect.TypeOf(&RoomState{})},
+		{"join", ":bla!bla@bla.tmi.twitch.tv JOIN #lirik", reflect.TypeOf(&MembershipMessage{})},
+		{"names", ":julezdev.tmi.twitch.tv 353 julezdev = #lirik :julezdev", reflect.TypeOf(&NamesMessage{})},
+		{"resub", resub, reflect.TypeOf(&SubscriptionMessage{})},
+		{"raid", raid, reflect.TypeOf(&RaidEvent{})},
+		{"usernotice", "@msg-id=announcement :tmi.twitch.tv USERNOTICE #lirik :hello", reflect.TypeOf(&UserNoticeMessage{})},
+		{"unknown", ":tmi.twitch.tv RECONNECT", reflect.TypeOf(&RawMessage{})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := ParseMessage(tt.line)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := ParseEvent(msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if reflect.TypeOf(got) != tt.want {
+				t.Errorf("ParseEvent() = %T, want %v", got, tt.want)
+			}
+		})
+	}
+
+	msg := mustParseMessage(":tmi.twitch.tv RECONNECT")
+	raw, _ := ParseEvent(msg)
+
+	if want := (&RawMessage{Command: "RECONNECT", Raw: msg}); !reflect.DeepEqual(raw, want) {
+		t.Errorf("ParseEvent() = %v, want %v", raw, want)
+	}
+}
@@ -0,0 +1,84 @@
+package twitchirc
+
+import (
+	"sort"
+)
+
+// Fragment is a part of the text of a message, either plain text or an emote.
+type Fragment struct {
+	Text string
+
+	// Emote is the emote of the fragment, it is nil for plain text.
+	Emote *Emote
+
+	// Start and End are the positions of the first and the last rune of the fragment in the text.
+	Start int
+	End   int
+}
+
+// Fragments splits the text of the message into ordered text and emote fragments.
+//
+// The positions are counted in runes, so the fragments are correct for multi-byte characters.
+func (m *PrivateMessage) Fragments() []*Fragment {
+	return fragments(m.Text, m.Emotes)
+}
+
+// emoteOccurrence is a single occurrence of an emote in a text.
+type emoteOccurrence struct {
+	emote    *Emote
+	position EmotePosition
+}
+
+// fragments splits text into text and emote fragments.
+// Emote positions which overlap a previous emote are ignored.
+func fragments(text string, emotes []*Emote) []*Fragment {
+	runes := []rune(text)
+
+	var occurrences []emoteOccurrence
+	for _, emote := range emotes {
+		for _, position := range emote.Positions {
+			if position.Start < 0 || position.End >= len(runes) || position.Start > position.End {
+				continue
+			}
+
+			occurrences = append(occurrences, emoteOccurrence{emote: emote, position: position})
+		}
+	}
+
+	sort.SliceStable(occurrences, func(i, j int) bool {
+		return occurrences[i].position.Start < occurrences[j].position.Start
+	})
+
+	var result []*Fragment
+
+	appendText := func(start, end int) {
+		if start <= end {
+			result = append(result, &Fragment{Text: string(runes[start : end+1]), Start: start, End: end})
+		}
+	}
+
+	next := 0
+
+	for _, occurrence := range occurrences {
+		position := occurrence.position
+
+		if position.Start < next {
+			continue
+		}
+
+		appendText(next, position.Start-1)
+
+		result = append(result, &Fragment{
+			Text:  string(runes[position.Start : position.End+1]),
+			Emote: occurrence.emote,
+			Start: position.Start,
+			End:   position.End,
+		})
+
+		next = position.End + 1
+	}
+
+	appendText(next, len(runes)-1)
+
+	return result
+}
@@ -0,0 +1,43 @@
+package twitchirc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPrivateMessage_Fragments(t *testing.T) {
+	kappa := &Emote{ID: "25", Name: "Kappa", Count: 2, Positions: []EmotePosition{{3, 7}, {17, 21}}}
+	pog := &Emote{ID: "305954156", Name: "PogChamp", Count: 1, Positions: []EmotePosition{{9, 16}}}
+
+	msg := &PrivateMessage{
+		Text:   "😀é Kappa PogChampKappa 👍🏽",
+		Emotes: []*Emote{kappa, pog},
+	}
+
+	want := []*Fragment{
+		{Text: "😀é ", Start: 0, End: 2},
+		{Text: "Kappa", Emote: kappa, Start: 3, End: 7},
+		{Text: " ", Start: 8, End: 8},
+		{Text: "PogChamp", Emote: pog, Start: 9, End: 16},
+		{Text: "Kappa", Emote: kappa, Start: 17, End: 21},
+		{Text: " 👍🏽", Start: 22, End: 24},
+	}
+
+	if got := msg.Fragments(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Fragments() = %v, want %v", got, want)
+	}
+
+	parsed, err := ParsePrivateMessage(mustParseMessage(privEmote))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var text string
+	for _, fragment := range parsed.Fragments() {
+		text += fragment.Text
+	}
+
+	if text != parsed.Text {
+		t.Errorf("Fragments() joined = %q, want %q", text, parsed.Text)
+	}
+}
@@ -0,0 +1,144 @@
+package twitchirc
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultGiftBombTimeout is the default time a gift bomb waits for all its gifts.
+const defaultGiftBombTimeout = time.Second * 10
+
+// GiftBombEvent is a mystery gift grouped with all subscriptions which were gifted by it.
+type GiftBombEvent struct {
+	Channel string
+
+	// Gifter is the user who gifted the subscriptions.
+	Gifter *User
+
+	// Anonymous is set if the gifter is anonymous.
+	Anonymous bool
+
+	// Count is the number of gifts announced by the mystery gift.
+	Count int
+
+	// Recipients are the users who received a subscription, in the order the gifts were received.
+	Recipients []*User
+
+	// Complete is set if all announced gifts were received before the timeout.
+	// A mystery gift without an origin id is fired right away without recipients and is not complete.
+	Complete bool
+
+	OriginID string
+	Plan     SubPlan
+
+	// MysteryGift is the message which announced the gifts.
+	MysteryGift *MysteryGiftMessage
+}
+
+// pendingGiftBomb is a gift bomb which waits for its gifts.
+type pendingGiftBomb struct {
+	conn  *Connection
+	event *GiftBombEvent
+	timer *time.Timer
+}
+
+// giftBombs groups the gifts of mystery gifts by their origin id.
+// The zero value is ready to use.
+type giftBombs struct {
+	lock    sync.Mutex
+	pending map[string]*pendingGiftBomb
+}
+
+// start begins collecting the gifts of the mystery gift.
+// fire gets called with the event once all gifts were received or timeout passed.
+func (g *giftBombs) start(conn *Connection, mystery *MysteryGiftMessage, timeout time.Duration, fire func(*Connection, *GiftBombEvent)) {
+	event := &GiftBombEvent{
+		Channel:     mystery.Channel,
+		Gifter:      mystery.User,
+		Anonymous:   mystery.Anonymous,
+		Count:       mystery.Count,
+		OriginID:    mystery.OriginID,
+		Plan:        mystery.Plan,
+		MysteryGift: mystery,
+	}
+
+	if event.Count <= 0 {
+		event.Complete = true
+		fire(conn, event)
+		return
+	}
+
+	// Without an origin id the gifts can't be matched to their mystery gift, so they are not collected.
+	// Two gift bombs in the same channel would get mixed up otherwise.
+	if mystery.OriginID == "" {
+		fire(conn, event)
+		return
+	}
+
+	if timeout <= 0 {
+		timeout = defaultGiftBombTimeout
+	}
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if g.pending == nil {
+		g.pending = make(map[string]*pendingGiftBomb)
+	}
+
+	originID := mystery.OriginID
+
+	g.pending[originID] = &pendingGiftBomb{
+		conn:  conn,
+		event: event,
+		timer: time.AfterFunc(timeout, func() {
+			if bomb := g.remove(originID); bomb != nil {
+				fire(bomb.conn, bomb.event)
+			}
+		}),
+	}
+}
+
+// add adds the gift to its gift bomb and reports whether the gift belongs to a gift bomb.
+// fire gets called with the event if it was the last gift of the gift bomb.
+func (g *giftBombs) add(gift *GiftSubMessage, fire func(*Connection, *GiftBombEvent)) bool {
+	g.lock.Lock()
+
+	bomb, ok := g.pending[gift.OriginID]
+	if !ok || gift.OriginID == "" {
+		g.lock.Unlock()
+		return false
+	}
+
+	bomb.event.Recipients = append(bomb.event.Recipients, gift.Recipient)
+
+	if len(bomb.event.Recipients) < bomb.event.Count {
+		g.lock.Unlock()
+		return true
+	}
+
+	bomb.timer.Stop()
+	bomb.event.Complete = true
+	delete(g.pending, gift.OriginID)
+
+	g.lock.Unlock()
+
+	fire(bomb.conn, bomb.event)
+
+	return true
+}
+
+// remove removes the gift bomb of originID and returns it if it was still pending.
+func (g *giftBombs) remove(originID string) *pendingGiftBomb {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	bomb, ok := g.pending[originID]
+	if !ok {
+		return nil
+	}
+
+	delete(g.pending, originID)
+
+	return bomb
+}
@@ -0,0 +1,108 @@
+package twitchirc
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChannelHandler_OnGiftBomb(t *testing.T) {
+	mystery := strings.Replace(mysteryGift, "msg-param-mass-gift-count=50", "msg-param-mass-gift-count=2", 1)
+
+	t.Run("complete", func(t *testing.T) {
+		events := make(chan *GiftBombEvent, 1)
+		var single int
+
+		handler := &ChannelHandler{
+			OnGiftSub:  func(*Connection, *GiftSubMessage) { single++ },
+			OnGiftBomb: func(_ *Connection, e *GiftBombEvent) { events <- e },
+		}
+
+		for _, line := range []string{mystery, subGift, subGift} {
+			if err := handler.HandleIRC(nil, mustParseMessage(line)); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		select {
+		case e := <-events:
+			if !e.Complete || !e.Anonymous || e.Count != 2 || len(e.Recipients) != 2 || e.Plan != SubPlanTier2 {
+				t.Errorf("OnGiftBomb() = %+v, want a complete anonymous gift bomb with 2 recipients", e)
+			}
+		default:
+			t.Fatal("OnGiftBomb() was not called")
+		}
+
+		if single != 0 {
+			t.Errorf("OnGiftSub() called %d times for grouped gifts, want 0", single)
+		}
+
+		// gifts without a mystery gift are not grouped
+		if err := handler.HandleIRC(nil, mustParseMessage(subGift)); err != nil {
+			t.Fatal(err)
+		}
+
+		if single != 1 {
+			t.Errorf("OnGiftSub() called %d times for a single gift, want 1", single)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		events := make(chan *GiftBombEvent, 1)
+
+		handler := &ChannelHandler{
+			OnGiftBomb:      func(_ *Connection, e *GiftBombEvent) { events <- e },
+			GiftBombTimeout: time.Millisecond * 10,
+		}
+
+		for _, line := range []string{mystery, subGift} {
+			if err := handler.HandleIRC(nil, mustParseMessage(line)); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		select {
+		case e := <-events:
+			if e.Complete || len(e.Recipients) != 1 {
+				t.Errorf("OnGiftBomb() = %+v, want an incomplete gift bomb with 1 recipient", e)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("OnGiftBomb() was not called after the timeout")
+		}
+	})
+	t.Run("without-origin-id", func(t *testing.T) {
+		events := make(chan *GiftBombEvent, 2)
+		var single int
+
+		handler := &ChannelHandler{
+			OnGiftSub:  func(*Connection, *GiftSubMessage) { single++ },
+			OnGiftBomb: func(_ *Connection, e *GiftBombEvent) { events <- e },
+		}
+
+		withoutOrigin := func(line string) string {
+			return strings.Replace(line, `msg-param-origin-id=da\s39\sa3\see\s5e\s6b\s4b\s0d;`, "", 1)
+		}
+
+		// two gift bombs at the same time can't be told apart, so none of them collects the gifts
+		for _, line := range []string{mystery, mystery, subGift, subGift} {
+			if err := handler.HandleIRC(nil, mustParseMessage(withoutOrigin(line))); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for i := 0; i < 2; i++ {
+			select {
+			case e := <-events:
+				if e.Complete || len(e.Recipients) != 0 || e.Count != 2 {
+					t.Errorf("OnGiftBomb() = %+v, want an incomplete gift bomb without recipients", e)
+				}
+			default:
+				t.Fatal("OnGiftBomb() was not called right away")
+			}
+		}
+
+		if single != 2 {
+			t.Errorf("OnGiftSub() called %d times, want 2", single)
+		}
+	})
+}
@@ -1,6 +1,10 @@
 package twitchirc
 
-import "github.com/pkg/errors"
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
 
 // Handler is a generic interface which provides the parsed IRC message from the reader.
 // It allows to customize the behavior on how a IRC message is handled.
@@ -11,6 +15,58 @@ type Handler interface {
 	HandleIRC(*Connection, *Message) error
 }
 
+// ConnectionState describes a change in the lifecycle of a connection.
+type ConnectionState int
+
+const (
+	// StateDisconnected means the connection to the server was lost.
+	StateDisconnected ConnectionState = iota
+	// StateReconnecting means the connection waits before it tries to reconnect.
+	StateReconnecting
+	// StateReconnected means the connection was created again and all channels got queued to be joined again.
+	// The joins are sent with the join limit, JoinHandler reports once they were sent.
+	StateReconnected
+)
+
+// StateChange holds the details of a lifecycle change of a connection.
+type StateChange struct {
+	State ConnectionState
+
+	// Attempt is the current reconnect attempt starting at 1.
+	Attempt int
+
+	// Delay is the time the connection waits before the reconnect attempt.
+	Delay time.Duration
+
+	// Err is the error which caused the disconnect or the error of the last failed attempt.
+	Err error
+}
+
+// StateHandler is an optional interface for the handler passed to Client.Connect.
+//
+// If the handler implements it, it gets notified about lifecycle changes of the connection
+// like disconnects and reconnects.
+type StateHandler interface {
+	HandleState(*Connection, *StateChange)
+}
+
+// JoinHandler is an optional interface for the handler passed to Client.Connect.
+//
+// If the handler implements it, it gets notified about the progress of the joins
+// scheduled by Connection.Join and about channels which could not be joined.
+type JoinHandler interface {
+	HandleJoinProgress(*Connection, *JoinProgress)
+	HandleJoinError(*Connection, *JoinError)
+}
+
+// ParseErrorHandler is an optional interface for the handler passed to Client.Connect.
+//
+// If the handler implements it, it gets notified about the messages which were dropped
+// because they could not be parsed in strict mode.
+type ParseErrorHandler interface {
+	HandleParseError(*Connection, *ParseError)
+}
+
 // ChannelHandler is a default implementation of Handler which holds all callback functions for chat events.
 //
 // It provides multiple callbacks for various chat events which occur in a chat room.
@@ -18,6 +74,59 @@ type Handler interface {
 type ChannelHandler struct {
 	OnPrivateMessage   func(*Connection, *PrivateMessage)
 	OnClearchatMessage func(*Connection, *ClearChatMessage)
+
+	// OnCheer gets called for every PRIVMSG with bits, after OnPrivateMessage.
+	OnCheer func(*Connection, *PrivateMessage)
+
+	// OnRewardRedemption gets called for every PRIVMSG sent with channel points, after OnPrivateMessage.
+	// This includes custom rewards with a message, highlighted messages and messages which skip the subs only mode.
+	OnRewardRedemption func(*Connection, *PrivateMessage)
+
+	// OnClearMessage gets called when a moderator deleted a single message.
+	OnClearMessage func(*Connection, *ClearMessage)
+
+	// OnNotice gets called for every NOTICE about the channel.
+	OnNotice func(*Connection, *NoticeMessage)
+
+	// OnUserJoin and OnUserPart get called if a user joined or departed the channel.
+	// They require Config.CaptureMembership and don't get called for duplicate JOIN and PART messages.
+	OnUserJoin func(*Connection, *MembershipMessage)
+	OnUserPart func(*Connection, *MembershipMessage)
+
+	// OnNames gets called for every NAMES reply after the channel was joined.
+	// The chatters of all replies are available through Connection.Chatters.
+	OnNames func(*Connection, *NamesMessage)
+
+	// OnUserNotice gets called for every USERNOTICE, including the ones with a specialized callback.
+	OnUserNotice     func(*Connection, *UserNoticeMessage)
+	OnSubscription   func(*Connection, *SubscriptionMessage)
+	OnGiftSub        func(*Connection, *GiftSubMessage)
+	OnMysteryGiftSub func(*Connection, *MysteryGiftMessage)
+	OnGiftUpgrade    func(*Connection, *GiftUpgradeMessage)
+	OnRaid           func(*Connection, *RaidEvent)
+	OnUnraid         func(*Connection, *UnraidEvent)
+	OnBitsBadgeTier  func(*Connection, *BitsBadgeTierMessage)
+
+	// OnRoomStateChange gets called for every ROOMSTATE with the room state before and after the update.
+	OnRoomStateChange func(*Connection, *RoomStateChange)
+
+	// OnUserState gets called with the state of the connected user in the channel.
+	OnUserState func(*Connection, *UserState)
+
+	// OnGiftBomb enables grouping the gifts of a mystery gift into one GiftBombEvent.
+	// It gets called once all announced gifts were received or GiftBombTimeout passed,
+	// in the latter case it is called from another goroutine.
+	// Gifts which belong to a mystery gift are not passed to OnGiftSub while OnGiftBomb is set.
+	OnGiftBomb func(*Connection, *GiftBombEvent)
+
+	// GiftBombTimeout is the time to wait for all gifts of a mystery gift. Defaults to 10 seconds.
+	GiftBombTimeout time.Duration
+
+	// Threads enables tracking the reply threads of the channel.
+	// Every PRIVMSG is added before OnPrivateMessage gets called.
+	Threads *ThreadTracker
+
+	giftBombs giftBombs
 }
 
 // HandleIRC parses the message to a specialized struct and calls the corresponding
@@ -25,19 +134,33 @@ type ChannelHandler struct {
 func (ch *ChannelHandler) HandleIRC(conn *Connection, msg *Message) error {
 	switch msg.Command {
 	case "PRIVMSG":
-		if ch.OnPrivateMessage != nil {
-			privMSG, err := parsePrivateMessage(msg)
+		if ch.OnPrivateMessage != nil || ch.OnCheer != nil || ch.OnRewardRedemption != nil || ch.Threads != nil {
+			privMSG, err := ParsePrivateMessage(msg)
 
 			if err != nil {
 				return errors.Wrapf(err, "chatHandler.HandleIRC: could not parse privmsg: %#v", msg)
 			}
 
-			ch.OnPrivateMessage(conn, privMSG)
+			if ch.Threads != nil {
+				ch.Threads.Add(privMSG)
+			}
+
+			if ch.OnPrivateMessage != nil {
+				ch.OnPrivateMessage(conn, privMSG)
+			}
+
+			if ch.OnCheer != nil && privMSG.Bits > 0 {
+				ch.OnCheer(conn, privMSG)
+			}
+
+			if ch.OnRewardRedemption != nil && privMSG.IsRedemption() {
+				ch.OnRewardRedemption(conn, privMSG)
+			}
 		}
 
 	case "CLEARCHAT":
 		if ch.OnClearchatMessage != nil {
-			clearchatMSG, err := parseClearChat(msg)
+			clearchatMSG, err := ParseClearChat(msg)
 
 			if err != nil {
 				return errors.Wrapf(err, "chatHandler.HandleIRC: could not parse clearchat: %#v", msg)
@@ -45,17 +168,174 @@ func (ch *ChannelHandler) HandleIRC(conn *Connection, msg *Message) error {
 
 			ch.OnClearchatMessage(conn, clearchatMSG)
 		}
+
+	case "CLEARMSG":
+		if ch.OnClearMessage != nil {
+			clearMSG, err := ParseClearMessage(msg)
+
+			if err != nil {
+				return errors.Wrapf(err, "chatHandler.HandleIRC: could not parse clearmsg: %#v", msg)
+			}
+
+			ch.OnClearMessage(conn, clearMSG)
+		}
+
+	case "ROOMSTATE":
+		if ch.OnRoomStateChange != nil {
+			change := conn.roomStateChange(messageChannel(msg), msg)
+
+			// The connection did not track the room state, so there is no previous state.
+			if change == nil {
+				state := RoomState{}.merge(msg)
+				change = &RoomStateChange{Channel: state.Channel, New: state, Raw: msg}
+			}
+
+			ch.OnRoomStateChange(conn, change)
+		}
+
+	case "USERSTATE":
+		if ch.OnUserState != nil {
+			userState, err := conn.cachedUserState(msg)
+
+			if err != nil {
+				return errors.Wrapf(err, "chatHandler.HandleIRC: could not parse userstate: %#v", msg)
+			}
+
+			ch.OnUserState(conn, userState)
+		}
+
+	case "NOTICE":
+		if ch.OnNotice != nil {
+			notice, err := ParseNotice(msg)
+
+			if err != nil {
+				return errors.Wrapf(err, "chatHandler.HandleIRC: could not parse notice: %#v", msg)
+			}
+
+			ch.OnNotice(conn, notice)
+		}
+
+	case "JOIN", "PART":
+		callback := ch.OnUserJoin
+		if msg.Command == "PART" {
+			callback = ch.OnUserPart
+		}
+
+		if callback != nil && conn.membershipChanged(messageChannel(msg), msg) {
+			membership, err := ParseMembership(msg)
+
+			if err != nil {
+				return errors.Wrapf(err, "chatHandler.HandleIRC: could not parse membership: %#v", msg)
+			}
+
+			callback(conn, membership)
+		}
+
+	case "353":
+		if ch.OnNames != nil {
+			names, err := ParseNames(msg)
+
+			if err != nil {
+				return errors.Wrapf(err, "chatHandler.HandleIRC: could not parse names: %#v", msg)
+			}
+
+			ch.OnNames(conn, names)
+		}
+
+	case "USERNOTICE":
+		userNotice, err := ParseUserNotice(msg)
+
+		if err != nil {
+			return errors.Wrapf(err, "chatHandler.HandleIRC: could not parse usernotice: %#v", msg)
+		}
+
+		ch.handleUserNotice(conn, userNotice)
 	}
 
 	return nil
 }
 
+// handleUserNotice calls the callback functions for the type of the USERNOTICE.
+func (ch *ChannelHandler) handleUserNotice(conn *Connection, userNotice *UserNoticeMessage) {
+	if ch.OnUserNotice != nil {
+		ch.OnUserNotice(conn, userNotice)
+	}
+
+	switch userNotice.MsgID {
+	case "sub", "resub":
+		if ch.OnSubscription != nil {
+			ch.OnSubscription(conn, parseSubscription(userNotice))
+		}
+
+	case "subgift", "anonsubgift":
+		gift := parseGiftSub(userNotice)
+
+		if ch.OnGiftBomb != nil && ch.giftBombs.add(gift, ch.OnGiftBomb) {
+			return
+		}
+
+		if ch.OnGiftSub != nil {
+			ch.OnGiftSub(conn, gift)
+		}
+
+	case "submysterygift", "anonsubmysterygift":
+		mystery := parseMysteryGift(userNotice)
+
+		if ch.OnMysteryGiftSub != nil {
+			ch.OnMysteryGiftSub(conn, mystery)
+		}
+
+		if ch.OnGiftBomb != nil {
+			ch.giftBombs.start(conn, mystery, ch.GiftBombTimeout, ch.OnGiftBomb)
+		}
+
+	case "giftpaidupgrade", "anongiftpaidupgrade":
+		if ch.OnGiftUpgrade != nil {
+			ch.OnGiftUpgrade(conn, parseGiftUpgrade(userNotice))
+		}
+
+	case "raid":
+		if ch.OnRaid != nil {
+			ch.OnRaid(conn, parseRaid(userNotice))
+		}
+
+	case "unraid":
+		if ch.OnUnraid != nil {
+			ch.OnUnraid(conn, parseUnraid(userNotice))
+		}
+
+	case "bitsbadgetier":
+		if ch.OnBitsBadgeTier != nil {
+			ch.OnBitsBadgeTier(conn, parseBitsBadgeTier(userNotice))
+		}
+	}
+}
+
 // IRCHandler is a default implementation of Handler which holds all callback functions for gerneral IRC Events.
 //
 // Only callback functions which are not limited to specific rooms are provided here.
 type IRCHandler struct {
 	OnPing    func(*Connection)
 	OnWhisper func(*Connection, *WhisperMessage)
+
+	// OnGlobalUserState gets called with the connected user after the login.
+	OnGlobalUserState func(*Connection, *UserState)
+
+	// OnNotice gets called for every NOTICE which is not about a channel, like a failed login.
+	OnNotice func(*Connection, *NoticeMessage)
+
+	OnDisconnect   func(*Connection, error)
+	OnReconnecting func(conn *Connection, attempt int, delay time.Duration)
+
+	// OnReconnect gets called once the connection was created again, the channels are only queued
+	// to be joined at that point. OnJoinProgress reports the sent joins.
+	OnReconnect func(*Connection)
+
+	OnJoinProgress func(*Connection, *JoinProgress)
+	OnJoinError    func(*Connection, *JoinError)
+
+	// OnParseError gets called for every message which was dropped in strict mode.
+	OnParseError func(*Connection, *ParseError)
 }
 
 // HandleIRC parses the message to a specialized struct and calls the corresponding
@@ -70,7 +350,7 @@ func (h *IRCHandler) HandleIRC(conn *Connection, msg *Message) error {
 
 	case "WHISPER":
 		if h.OnWhisper != nil {
-			whisperMSG, err := parseWhisper(msg)
+			whisperMSG, err := ParseWhisper(msg)
 
 			if err != nil {
 				return errors.Wrapf(err, "IRCHandler.HandleIRC: could not parse whisper: %#v", msg)
@@ -78,7 +358,70 @@ func (h *IRCHandler) HandleIRC(conn *Connection, msg *Message) error {
 
 			h.OnWhisper(conn, whisperMSG)
 		}
+
+	case "GLOBALUSERSTATE":
+		if h.OnGlobalUserState != nil {
+			userState, err := conn.cachedUserState(msg)
+
+			if err != nil {
+				return errors.Wrapf(err, "IRCHandler.HandleIRC: could not parse globaluserstate: %#v", msg)
+			}
+
+			h.OnGlobalUserState(conn, userState)
+		}
+
+	case "NOTICE":
+		if h.OnNotice != nil {
+			notice, err := ParseNotice(msg)
+
+			if err != nil {
+				return errors.Wrapf(err, "IRCHandler.HandleIRC: could not parse notice: %#v", msg)
+			}
+
+			h.OnNotice(conn, notice)
+		}
 	}
 
 	return nil
 }
+
+// HandleState calls the callback function for the lifecycle change.
+func (h *IRCHandler) HandleState(conn *Connection, change *StateChange) {
+	switch change.State {
+	case StateDisconnected:
+		if h.OnDisconnect != nil {
+			h.OnDisconnect(conn, change.Err)
+		}
+
+	case StateReconnecting:
+		if h.OnReconnecting != nil {
+			h.OnReconnecting(conn, change.Attempt, change.Delay)
+		}
+
+	case StateReconnected:
+		if h.OnReconnect != nil {
+			h.OnReconnect(conn)
+		}
+	}
+}
+
+// HandleJoinProgress calls the OnJoinProgress callback function.
+func (h *IRCHandler) HandleJoinProgress(conn *Connection, progress *JoinProgress) {
+	if h.OnJoinProgress != nil {
+		h.OnJoinProgress(conn, progress)
+	}
+}
+
+// HandleJoinError calls the OnJoinError callback function.
+func (h *IRCHandler) HandleJoinError(conn *Connection, joinErr *JoinError) {
+	if h.OnJoinError != nil {
+		h.OnJoinError(conn, joinErr)
+	}
+}
+
+// HandleParseError calls the OnParseError callback function.
+func (h *IRCHandler) HandleParseError(conn *Connection, parseErr *ParseError) {
+	if h.OnParseError != nil {
+		h.OnParseError(conn, parseErr)
+	}
+}
@@ -5,6 +5,7 @@ package twitchirc
 import (
 	"bytes"
 	"errors"
+	"sort"
 	"strings"
 )
 
@@ -16,6 +17,14 @@ var tagDecodeSlashMap = map[rune]rune{
 	'n':  '\n',
 }
 
+var tagEncodeSlashMap = map[rune]string{
+	';':  "\\:",
+	' ':  "\\s",
+	'\\': "\\\\",
+	'\r': "\\r",
+	'\n': "\\n",
+}
+
 var (
 	// ErrZeroLengthMessage is returned when parsing if the input is
 	// zero-length.
@@ -32,6 +41,10 @@ var (
 	// ErrMissingCommand is returned when parsing if there is no
 	// command in the parsed message.
 	ErrMissingCommand = errors.New("irc: missing message command")
+
+	// ErrInvalidParam is returned when encoding if a param contains a line break
+	// or if a param other than the last one is empty, contains a space or starts with a colon.
+	ErrInvalidParam = errors.New("irc: param can't be encoded")
 )
 
 // TagValue represents the value of a tag.
@@ -73,6 +86,21 @@ func parseTagValue(v string) TagValue {
 	return TagValue(ret.String())
 }
 
+// encodeTagValue escapes a TagValue so it can be sent to the connection.
+func encodeTagValue(v TagValue) string {
+	ret := &strings.Builder{}
+
+	for _, c := range string(v) {
+		if replacement, ok := tagEncodeSlashMap[c]; ok {
+			ret.WriteString(replacement)
+		} else {
+			ret.WriteRune(c)
+		}
+	}
+
+	return ret.String()
+}
+
 // Tags represents the IRCv3 message tags.
 type Tags map[string]TagValue
 
@@ -156,21 +184,26 @@ type Message struct {
 // mustParseMessage calls ParseMessage and either returns the message
 // or panics if an error is returned.
 func mustParseMessage(line string) *Message {
-	m, err := parseMessage(line)
+	m, err := ParseMessage(line)
 	if err != nil {
 		panic(err.Error())
 	}
 	return m
 }
 
-// parseMessage takes a message string (usually a whole line) and
+// ParseMessage takes a message string (usually a whole line) and
 // parses it into a Message struct. This will return nil in the case
 // of invalid messages.
-func parseMessage(line string) (*Message, error) {
+//
+// The returned error is a *ParseError which wraps one of ErrZeroLengthMessage,
+// ErrMissingDataAfterTags, ErrMissingDataAfterPrefix or ErrMissingCommand.
+// Compare it with errors.Is, the error is never equal to the sentinel itself.
+// The Offset of the error is the start of the malformed section of the line.
+func ParseMessage(line string) (*Message, error) {
 	// Trim the line and make sure we have data
 	line = strings.TrimRight(line, "\r\n")
 	if len(line) == 0 {
-		return nil, ErrZeroLengthMessage
+		return nil, &ParseError{Field: "line", Raw: line, Err: ErrZeroLengthMessage}
 	}
 
 	c := &Message{
@@ -179,10 +212,12 @@ func parseMessage(line string) (*Message, error) {
 		Message: line,
 	}
 
+	raw := line
+
 	if line[0] == '@' {
 		loc := strings.Index(line, " ")
-		if loc == -1 {
-			return nil, ErrMissingDataAfterTags
+		if loc == -1 || loc == len(line)-1 {
+			return nil, &ParseError{Field: "tags", Offset: 0, Raw: raw, Err: ErrMissingDataAfterTags}
 		}
 
 		c.Tags = parseTags(line[1:loc])
@@ -191,8 +226,8 @@ func parseMessage(line string) (*Message, error) {
 
 	if line[0] == ':' {
 		loc := strings.Index(line, " ")
-		if loc == -1 {
-			return nil, ErrMissingDataAfterPrefix
+		if loc == -1 || loc == len(line)-1 {
+			return nil, &ParseError{Field: "prefix", Offset: len(raw) - len(line), Raw: raw, Err: ErrMissingDataAfterPrefix}
 		}
 
 		// Parse the identity, if there was one
@@ -212,7 +247,7 @@ func parseMessage(line string) (*Message, error) {
 	// If there are no args, we need to bail because we need at
 	// least the command.
 	if len(c.Params) == 0 {
-		return nil, ErrMissingCommand
+		return nil, &ParseError{Field: "command", Offset: len(raw) - len(line), Raw: raw, Err: ErrMissingCommand}
 	}
 
 	// If we had a trailing arg, append it to the other args
@@ -233,3 +268,98 @@ func parseMessage(line string) (*Message, error) {
 
 	return c, nil
 }
+
+// String returns the prefix as it is sent on the wire, without the leading colon.
+func (p *Prefix) String() string {
+	ret := &strings.Builder{}
+	ret.WriteString(p.Name)
+
+	if p.User != "" {
+		ret.WriteByte('!')
+		ret.WriteString(p.User)
+	}
+
+	if p.Host != "" {
+		ret.WriteByte('@')
+		ret.WriteString(p.Host)
+	}
+
+	return ret.String()
+}
+
+// MarshalText encodes the message into a line without the trailing \r\n.
+//
+// The tags are sorted by their key and their values get escaped. The last param
+// is sent as trailing param if there are multiple params or if it is empty, contains
+// a space or starts with a colon.
+// ParseMessage returns the same message for the encoded line, except for the Message field.
+func (m *Message) MarshalText() ([]byte, error) {
+	if m.Command == "" {
+		return nil, ErrMissingCommand
+	}
+
+	buf := &bytes.Buffer{}
+
+	if len(m.Tags) > 0 {
+		keys := make([]string, 0, len(m.Tags))
+		for key := range m.Tags {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		buf.WriteByte('@')
+
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteByte(';')
+			}
+
+			buf.WriteString(key)
+			buf.WriteByte('=')
+			buf.WriteString(encodeTagValue(m.Tags[key]))
+		}
+
+		buf.WriteByte(' ')
+	}
+
+	if m.Prefix != nil && m.Prefix.Name != "" {
+		buf.WriteByte(':')
+		buf.WriteString(m.Prefix.String())
+		buf.WriteByte(' ')
+	}
+
+	buf.WriteString(m.Command)
+
+	for i, param := range m.Params {
+		buf.WriteByte(' ')
+
+		if strings.ContainsAny(param, "\r\n\x00") {
+			return nil, ErrInvalidParam
+		}
+
+		invalid := param == "" || strings.HasPrefix(param, ":") || strings.Contains(param, " ")
+
+		// Like twitch the last of multiple params is always sent as trailing param.
+		if i == len(m.Params)-1 && (invalid || i > 0) {
+			buf.WriteByte(':')
+		} else if invalid {
+			return nil, ErrInvalidParam
+		}
+
+		buf.WriteString(param)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// String returns the message encoded by MarshalText.
+// It returns an empty string if the message can't be encoded.
+func (m *Message) String() string {
+	line, err := m.MarshalText()
+	if err != nil {
+		return ""
+	}
+
+	return string(line)
+}
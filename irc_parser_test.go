@@ -0,0 +1,72 @@
+package twitchirc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMessage_MarshalText(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+	}{
+		{"whisper-emote", whisperEmote},
+		{"whisper-emote-badge", whisperEmoteBadge},
+		{"priv-emote", privEmote},
+		{"timeout", timeout},
+		{"ban", ban},
+		{"clear-message", clearMessage},
+		{"priv-reply", privReply},
+		{"ping", "PING :tmi.twitch.tv"},
+		{"escaped-tags", `@system-msg=julezdev\ssubscribed\:\sgreat\\stream\r\n;flags= :tmi.twitch.tv USERNOTICE #lirik`},
+		{"empty-trailing", ":tmi.twitch.tv NOTICE #lirik :"},
+		{"colon-trailing", ":julezdev!julezdev@julezdev.tmi.twitch.tv PRIVMSG #julezdev ::)"},
+		{"names", ":julezdev.tmi.twitch.tv 353 julezdev = #lirik :julezdev lirik"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := mustParseMessage(tt.line)
+
+			line, err := want.MarshalText()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := ParseMessage(string(line))
+			if err != nil {
+				t.Fatalf("ParseMessage(%q) error = %v", line, err)
+			}
+
+			if got.Message != string(line) {
+				t.Errorf("ParseMessage() Message = %q, want %q", got.Message, line)
+			}
+
+			got.Message, want.Message = "", ""
+
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("ParseMessage(MarshalText()) = %#v, want %#v", got, want)
+			}
+		})
+	}
+}
+
+func TestMessage_String(t *testing.T) {
+	msg := &Message{
+		Tags:    Tags{"reply-parent-msg-id": "b34ccfc7", "client-nonce": "a b;c"},
+		Prefix:  &Prefix{Name: "julezdev", User: "julezdev", Host: "julezdev.tmi.twitch.tv"},
+		Command: "PRIVMSG",
+		Params:  []string{"#julezdev", "hello world"},
+	}
+
+	want := `@client-nonce=a\sb\:c;reply-parent-msg-id=b34ccfc7 :julezdev!julezdev@julezdev.tmi.twitch.tv PRIVMSG #julezdev :hello world`
+	if got := msg.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	msg.Params = []string{"#julez dev", "hello"}
+
+	if _, err := msg.MarshalText(); err != ErrInvalidParam {
+		t.Errorf("MarshalText() error = %v, want %v", err, ErrInvalidParam)
+	}
+}
@@ -0,0 +1,480 @@
+package twitchirc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// maxLineLength is the maximum length of an IRC line including the trailing \r\n.
+const maxLineLength = 512
+
+// JoinLimit defines how many channels can be joined in a time window.
+//
+// Twitch counts the joins of all connections of an user together.
+type JoinLimit struct {
+	// Joins is the number of channels which can be joined in Window.
+	Joins int
+
+	// Window is the time window of the limit.
+	Window time.Duration
+}
+
+var (
+	// DefaultJoinLimit is the join limit of a normal twitch account.
+	DefaultJoinLimit = JoinLimit{Joins: 20, Window: time.Second * 10}
+
+	// VerifiedBotJoinLimit is the join limit of a verified bot.
+	VerifiedBotJoinLimit = JoinLimit{Joins: 2000, Window: time.Second * 10}
+)
+
+var (
+	// ErrJoinTimeout is reported if a join was not confirmed before the context was done.
+	ErrJoinTimeout = errors.New("twitchirc: join was not confirmed in time")
+
+	// ErrChannelSuspended is reported if the channel is suspended or closed.
+	ErrChannelSuspended = errors.New("twitchirc: channel is suspended")
+
+	// ErrBanned is reported if the user is banned from the channel.
+	ErrBanned = errors.New("twitchirc: user is banned from the channel")
+
+	// ErrJoinRejected is reported if twitch rejected the join for another reason.
+	ErrJoinRejected = errors.New("twitchirc: join was rejected")
+
+	// errJoinDeparted is reported to waiting joins if the channel gets departed.
+	errJoinDeparted = errors.New("twitchirc: channel was departed before the join was confirmed")
+)
+
+// joinNoticeErrors maps the msg-id of NOTICE messages which reject a join to an error.
+var joinNoticeErrors = map[NoticeID]error{
+	NoticeMsgChannelSuspended: ErrChannelSuspended,
+	NoticeTOSBan:              ErrChannelSuspended,
+	NoticeMsgBanned:           ErrBanned,
+	NoticeMsgChannelBlocked:   ErrJoinRejected,
+}
+
+// JoinProgress reports the progress of the scheduled joins of a connection.
+type JoinProgress struct {
+	// Channels are the channels which were sent in the last JOIN.
+	Channels []string
+
+	// Sent is the number of channels sent since the join queue was empty.
+	Sent int
+
+	// Pending is the number of channels which still wait to be joined.
+	Pending int
+}
+
+// JoinError is reported if a channel could not be joined.
+type JoinError struct {
+	Channel string
+	Err     error
+}
+
+func (e *JoinError) Error() string {
+	return fmt.Sprintf("twitchirc: could not join channel %s: %v", e.Channel, e.Err)
+}
+
+// Unwrap returns the reason why the channel could not be joined.
+func (e *JoinError) Unwrap() error {
+	return e.Err
+}
+
+// joinLimiter limits the channels joined by all connections of a client.
+type joinLimiter struct {
+	limit JoinLimit
+
+	lock   sync.Mutex
+	window *slidingWindow
+}
+
+func newJoinLimiter(limit *JoinLimit) *joinLimiter {
+	l := DefaultJoinLimit
+	if limit != nil {
+		l = *limit
+	}
+
+	if l.Joins <= 0 {
+		l.Joins = DefaultJoinLimit.Joins
+	}
+
+	if l.Window <= 0 {
+		l.Window = DefaultJoinLimit.Window
+	}
+
+	return &joinLimiter{
+		limit:  l,
+		window: &slidingWindow{size: l.Window},
+	}
+}
+
+// reserve counts n joins and returns zero if they can be sent now.
+// Otherwise it returns the time until the joins could be sent and does not count them.
+func (l *joinLimiter) reserve(n int) time.Duration {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+
+	if wait := l.window.wait(now, l.limit.Joins, n); wait > 0 {
+		return wait
+	}
+
+	l.window.add(now, n)
+
+	return 0
+}
+
+// joinQueue holds the channels which wait to be joined and the channels which wait for a confirmation.
+// The zero value is an empty queue.
+type joinQueue struct {
+	lock    sync.Mutex
+	pending []string
+	queued  map[string]bool
+	sent    int
+	running bool
+	status  map[string]*channelJoin
+}
+
+// channelJoin is the join status of a channel.
+type channelJoin struct {
+	confirmed bool
+	waiters   []chan error
+}
+
+// resolve sends err to all waiters.
+func (cj *channelJoin) resolve(err error) {
+	for _, w := range cj.waiters {
+		w <- err
+	}
+
+	cj.waiters = nil
+}
+
+// scheduleJoin queues the channels and starts sending them if no joins are being sent.
+func (c *Connection) scheduleJoin(channels []string) {
+	q := &c.joins
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.queued == nil {
+		q.queued = make(map[string]bool)
+	}
+
+	c.expectJoin(channels)
+
+	for _, ch := range channels {
+		if !q.queued[ch] {
+			q.queued[ch] = true
+			q.pending = append(q.pending, ch)
+		}
+	}
+
+	if !q.running && len(q.pending) > 0 {
+		q.running = true
+		go c.sendJoins()
+	}
+}
+
+// unscheduleJoin removes channel from the queue if it was not sent yet.
+// Joins waiting for a confirmation of the channel fail.
+func (c *Connection) unscheduleJoin(channel string) {
+	q := &c.joins
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	delete(q.queued, channel)
+
+	if cj, ok := q.status[channel]; ok {
+		cj.resolve(&JoinError{Channel: channel, Err: errJoinDeparted})
+		delete(q.status, channel)
+	}
+}
+
+// expectJoin marks the channels as not confirmed.
+// A channel which is already waiting for a confirmation keeps its pending join,
+// every other channel gets a new pending join.
+// The caller must hold the lock of the queue.
+func (c *Connection) expectJoin(channels []string) {
+	q := &c.joins
+
+	if q.status == nil {
+		q.status = make(map[string]*channelJoin)
+	}
+
+	for _, ch := range channels {
+		if cj, ok := q.status[ch]; ok && !cj.confirmed {
+			continue
+		}
+
+		q.status[ch] = &channelJoin{}
+	}
+}
+
+// confirmJoin marks the channel as joined and notifies the waiting joins.
+func (c *Connection) confirmJoin(channel string) {
+	q := &c.joins
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	cj, ok := q.status[channel]
+	if !ok || cj.confirmed {
+		return
+	}
+
+	cj.confirmed = true
+	cj.resolve(nil)
+}
+
+// rejectJoin fails the join of the channel if msg is a NOTICE which rejects a not yet confirmed join.
+// The handler of the channel gets removed and the irc handler gets notified.
+func (c *Connection) rejectJoin(channel string, msg *Message) {
+	msgID, _ := msg.GetTag("msg-id")
+
+	reason, ok := joinNoticeErrors[NoticeID(msgID)]
+	if !ok {
+		return
+	}
+
+	q := &c.joins
+	q.lock.Lock()
+
+	cj, ok := q.status[channel]
+	if !ok || cj.confirmed {
+		q.lock.Unlock()
+		return
+	}
+
+	q.lock.Unlock()
+
+	c.failJoin(&JoinError{Channel: channel, Err: reason})
+}
+
+// failJoin fails the pending join of the channel of joinErr.
+// The waiting joins get joinErr, the handler of the channel gets removed and the irc handler gets notified.
+func (c *Connection) failJoin(joinErr *JoinError) {
+	q := &c.joins
+	q.lock.Lock()
+
+	if cj, ok := q.status[joinErr.Channel]; ok {
+		cj.resolve(joinErr)
+		delete(q.status, joinErr.Channel)
+	}
+
+	delete(q.queued, joinErr.Channel)
+	q.lock.Unlock()
+
+	c.handlerLock.Lock()
+	delete(c.channelHandler, joinErr.Channel)
+	c.handlerLock.Unlock()
+
+	c.emitJoinError(joinErr)
+}
+
+// JoinConfirmed joins the channel like Join and waits until twitch confirmed the join.
+//
+// The join is confirmed once a ROOMSTATE or USERSTATE of the channel was received,
+// this requires Config.CaptureCommands. If twitch rejects the join a *JoinError
+// wrapping ErrChannelSuspended, ErrBanned or ErrJoinRejected is returned.
+// If ctx is done before the join was confirmed, a *JoinError wrapping ErrJoinTimeout is returned.
+// The handler gets removed if the join failed.
+//
+// The confirmation is read by Run, so JoinConfirmed waits until ctx is done if the connection is not running.
+func (c *Connection) JoinConfirmed(ctx context.Context, channel string, handler Handler) error {
+	return <-c.JoinAsync(ctx, channel, handler)
+}
+
+// JoinAsync is the same as JoinConfirmed but returns immediately.
+// The result of the join gets sent into the returned channel.
+func (c *Connection) JoinAsync(ctx context.Context, channel string, handler Handler) <-chan error {
+	channel = strings.ToLower(channel)
+
+	result := make(chan error, 1)
+	wait := make(chan error, 1)
+
+	q := &c.joins
+	q.lock.Lock()
+
+	if cj, ok := q.status[channel]; ok && cj.confirmed {
+		q.lock.Unlock()
+		result <- nil
+		return result
+	}
+
+	c.expectJoin([]string{channel})
+	cj := q.status[channel]
+	cj.waiters = append(cj.waiters, wait)
+
+	q.lock.Unlock()
+
+	if err := c.JoinOne(channel, handler); err != nil {
+		c.abortJoin(channel, cj)
+		result <- &JoinError{Channel: channel, Err: err}
+		return result
+	}
+
+	go func() {
+		select {
+		case err := <-wait:
+			result <- err
+		case <-ctx.Done():
+			c.abortJoin(channel, cj)
+			result <- &JoinError{Channel: channel, Err: ErrJoinTimeout}
+		}
+	}()
+
+	return result
+}
+
+// abortJoin departs the channel if cj is still its pending join and was not confirmed yet.
+// A later join of the channel is not affected.
+func (c *Connection) abortJoin(channel string, cj *channelJoin) {
+	q := &c.joins
+
+	q.lock.Lock()
+	pending := q.status[channel] == cj && !cj.confirmed
+	q.lock.Unlock()
+
+	if !pending {
+		return
+	}
+
+	c.Depart(channel)
+}
+
+// sendJoins sends the queued channels in batches until the queue is empty.
+//
+// Each batch does not exceed the join limit and is sent as JOIN lines with comma separated channels.
+func (c *Connection) sendJoins() {
+	limiter := c.joinLimiter()
+	q := &c.joins
+
+	for {
+		q.lock.Lock()
+		batch := c.nextJoinBatch(limiter.limit.Joins)
+
+		if len(batch) == 0 {
+			q.running = false
+			q.sent = 0
+			q.lock.Unlock()
+			return
+		}
+
+		q.lock.Unlock()
+
+		err := c.waitReserved(func() time.Duration {
+			return limiter.reserve(len(batch))
+		})
+
+		if err == nil {
+			for _, line := range joinLines(batch) {
+				if err = c.Write(line); err != nil {
+					break
+				}
+			}
+		}
+
+		if err != nil {
+			for _, ch := range batch {
+				c.failJoin(&JoinError{Channel: ch, Err: errors.Wrap(err, "connection.sendJoins: could not send join")})
+			}
+
+			continue
+		}
+
+		q.lock.Lock()
+		q.sent += len(batch)
+		progress := &JoinProgress{Channels: batch, Sent: q.sent, Pending: len(q.queued)}
+		q.lock.Unlock()
+
+		c.emitJoinProgress(progress)
+	}
+}
+
+// nextJoinBatch removes up to max channels from the queue.
+// Channels which were departed before they were sent are skipped.
+// The caller must hold the lock of the queue.
+func (c *Connection) nextJoinBatch(max int) []string {
+	q := &c.joins
+
+	var batch []string
+
+	for len(q.pending) > 0 && len(batch) < max {
+		ch := q.pending[0]
+		q.pending = q.pending[1:]
+
+		if q.queued[ch] {
+			delete(q.queued, ch)
+			batch = append(batch, ch)
+		}
+	}
+
+	return batch
+}
+
+// joinLines creates JOIN lines with comma separated channels
+// which do not exceed the maximum line length.
+func joinLines(channels []string) []string {
+	var (
+		lines []string
+		batch []string
+	)
+
+	length := len("JOIN \r\n")
+
+	for _, ch := range channels {
+		// one byte for the # and one for the separating comma
+		if len(batch) > 0 && length+len(ch)+2 > maxLineLength {
+			lines = append(lines, "JOIN #"+strings.Join(batch, ",#"))
+			batch = nil
+			length = len("JOIN \r\n")
+		}
+
+		length += len(ch) + 2
+		batch = append(batch, ch)
+	}
+
+	if len(batch) > 0 {
+		lines = append(lines, "JOIN #"+strings.Join(batch, ",#"))
+	}
+
+	return lines
+}
+
+// joinLimiter returns the join limiter of the client or
+// the join limiter of the connection if the connection was not created by a client.
+func (c *Connection) joinLimiter() *joinLimiter {
+	if c.client != nil {
+		return c.client.joinLimiter()
+	}
+
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+
+	if c.localJoinLimiter == nil {
+		c.localJoinLimiter = newJoinLimiter(c.config.JoinLimit)
+	}
+
+	return c.localJoinLimiter
+}
+
+// emitJoinProgress passes the progress to the irc handler if it implements JoinHandler.
+func (c *Connection) emitJoinProgress(progress *JoinProgress) {
+	if h, ok := c.ircHandler.(JoinHandler); ok {
+		h.HandleJoinProgress(c, progress)
+	}
+}
+
+// emitJoinError passes the error to the irc handler if it implements JoinHandler.
+func (c *Connection) emitJoinError(joinErr *JoinError) {
+	if h, ok := c.ircHandler.(JoinHandler); ok {
+		h.HandleJoinError(c, joinErr)
+	}
+}
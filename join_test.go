@@ -0,0 +1,248 @@
+package twitchirc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_joinLines(t *testing.T) {
+	long := make([]string, 30)
+	for i := range long {
+		long[i] = strings.Repeat(string(rune('a'+i%26)), 25)
+	}
+
+	table := []struct {
+		name     string
+		channels []string
+		want     []string
+	}{
+		{
+			"empty",
+			nil,
+			nil,
+		},
+		{
+			"single",
+			[]string{"julezdev"},
+			[]string{"JOIN #julezdev"},
+		},
+		{
+			"batched",
+			[]string{"julezdev", "lirik", "xqcow"},
+			[]string{"JOIN #julezdev,#lirik,#xqcow"},
+		},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := joinLines(tt.channels); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("joinLines() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("max-line-length", func(t *testing.T) {
+		lines := joinLines(long)
+
+		if len(lines) != 2 {
+			t.Fatalf("joinLines() returned %d lines, want 2", len(lines))
+		}
+
+		for _, line := range lines {
+			if len(line)+2 > maxLineLength {
+				t.Errorf("joinLines() line has %d bytes, want at most %d", len(line)+2, maxLineLength)
+			}
+		}
+	})
+}
+
+func TestConnection_Join(t *testing.T) {
+	srv := newFakeServer()
+
+	progress := make(chan *JoinProgress, 10)
+
+	client := NewAnonymousClient(&Config{
+		JoinLimit: &JoinLimit{Joins: 2, Window: time.Millisecond * 50},
+	})
+	client.dialer = srv.dial
+
+	conn, err := client.Connect(&IRCHandler{
+		OnJoinProgress: func(_ *Connection, p *JoinProgress) { progress <- p },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	if err := conn.Join([]string{"a", "B", "c", "d", "e"}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	srv.expect(t, "JOIN #a,#b")
+	srv.expect(t, "JOIN #c,#d")
+	srv.expect(t, "JOIN #e")
+
+	want := []JoinProgress{
+		{Channels: []string{"a", "b"}, Sent: 2, Pending: 3},
+		{Channels: []string{"c", "d"}, Sent: 4, Pending: 1},
+		{Channels: []string{"e"}, Sent: 5, Pending: 0},
+	}
+
+	for _, w := range want {
+		select {
+		case got := <-progress:
+			if !reflect.DeepEqual(*got, w) {
+				t.Errorf("OnJoinProgress() = %v, want %v", *got, w)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("no join progress was reported")
+		}
+	}
+}
+
+func TestConnection_JoinConfirmed(t *testing.T) {
+	srv := newFakeServer()
+
+	client := NewAnonymousClient(&Config{CaptureCommands: true})
+	client.dialer = srv.dial
+
+	joinErrors := make(chan *JoinError, 1)
+	conn, err := client.Connect(&IRCHandler{
+		OnJoinError: func(_ *Connection, err *JoinError) { joinErrors <- err },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := srv.accept(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go conn.Run(ctx)
+
+	t.Run("confirmed", func(t *testing.T) {
+		result := conn.JoinAsync(ctx, "julezdev", nil)
+
+		srv.expect(t, "JOIN #julezdev")
+		fmt.Fprint(server, "@emote-only=0;followers-only=-1;r9k=0;room-id=530594933;slow=0;subs-only=0 :tmi.twitch.tv ROOMSTATE #julezdev\r\n")
+
+		if err := <-result; err != nil {
+			t.Fatalf("JoinAsync() error = %v", err)
+		}
+
+		// an already confirmed channel returns immediately
+		if err := conn.JoinConfirmed(ctx, "julezdev", nil); err != nil {
+			t.Fatalf("JoinConfirmed() error = %v", err)
+		}
+	})
+
+	t.Run("stale-abort", func(t *testing.T) {
+		conn.joins.lock.Lock()
+		stale := conn.joins.status["julezdev"]
+		// the channel gets joined again, like after a reconnect
+		conn.expectJoin([]string{"julezdev"})
+		conn.joins.lock.Unlock()
+
+		// the abort of the earlier join must not depart the channel
+		conn.abortJoin("julezdev", stale)
+
+		conn.handlerLock.RLock()
+		_, ok := conn.channelHandler["julezdev"]
+		conn.handlerLock.RUnlock()
+
+		if !ok {
+			t.Error("channel was departed by the abort of an earlier join")
+		}
+
+		fmt.Fprint(server, "@emote-only=0;followers-only=-1;r9k=0;room-id=530594933;slow=0;subs-only=0 :tmi.twitch.tv ROOMSTATE #julezdev\r\n")
+
+		if err := conn.JoinConfirmed(ctx, "julezdev", nil); err != nil {
+			t.Fatalf("JoinConfirmed() error = %v", err)
+		}
+	})
+
+	t.Run("suspended", func(t *testing.T) {
+		result := conn.JoinAsync(ctx, "suspended", nil)
+
+		srv.expect(t, "JOIN #suspended")
+		fmt.Fprint(server, "@msg-id=msg_channel_suspended :tmi.twitch.tv NOTICE #suspended :This channel has been suspended.\r\n")
+
+		err := <-result
+		if !errors.Is(err, ErrChannelSuspended) {
+			t.Fatalf("JoinAsync() error = %v, want %v", err, ErrChannelSuspended)
+		}
+
+		if got := <-joinErrors; got.Channel != "suspended" {
+			t.Errorf("OnJoinError() channel = %v, want %v", got.Channel, "suspended")
+		}
+
+		conn.handlerLock.RLock()
+		_, ok := conn.channelHandler["suspended"]
+		conn.handlerLock.RUnlock()
+
+		if ok {
+			t.Error("handler of the rejected channel was not removed")
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		timeoutCtx, cancel := context.WithTimeout(ctx, time.Millisecond*20)
+		defer cancel()
+
+		err := conn.JoinConfirmed(timeoutCtx, "silent", nil)
+		if !errors.Is(err, ErrJoinTimeout) {
+			t.Fatalf("JoinConfirmed() error = %v, want %v", err, ErrJoinTimeout)
+		}
+
+		srv.expect(t, "PART #silent")
+	})
+}
+
+func TestConnection_JoinConfirmed_writeFailed(t *testing.T) {
+	srv := newFakeServer()
+
+	client := NewAnonymousClient(&Config{CaptureCommands: true})
+	client.dialer = srv.dial
+
+	joinErrors := make(chan *JoinError, 1)
+	conn, err := client.Connect(&IRCHandler{
+		OnJoinError: func(_ *Connection, err *JoinError) { joinErrors <- err },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	// the JOIN can't be written anymore
+	srv.accept(t).Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	err = conn.JoinConfirmed(ctx, "julezdev", nil)
+
+	var joinErr *JoinError
+	if !errors.As(err, &joinErr) || errors.Is(err, ErrJoinTimeout) {
+		t.Fatalf("JoinConfirmed() error = %v, want the write error", err)
+	}
+
+	if got := <-joinErrors; got != joinErr {
+		t.Errorf("OnJoinError() = %v, want %v", got, joinErr)
+	}
+
+	conn.handlerLock.RLock()
+	_, ok := conn.channelHandler["julezdev"]
+	conn.handlerLock.RUnlock()
+
+	if ok {
+		t.Error("handler of the failed channel was not removed")
+	}
+}
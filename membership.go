@@ -0,0 +1,237 @@
+package twitchirc
+
+import (
+	"sort"
+	"strings"
+)
+
+// MembershipMessage represents a parsed JOIN or PART of a user.
+//
+// Twitch only sends them if Config.CaptureMembership is set.
+type MembershipMessage struct {
+	Channel string
+
+	// User is the login name of the user who joined or departed.
+	User string
+
+	Raw *Message
+}
+
+// ParseMembership parses a JOIN or PART.
+func ParseMembership(message *Message) (*MembershipMessage, error) {
+	membership := &MembershipMessage{
+		Raw: message,
+	}
+
+	if message.Prefix != nil {
+		membership.User = message.Prefix.Name
+	}
+
+	if len(message.Params) > 0 {
+		membership.Channel = strings.TrimPrefix(message.Params[0], "#")
+	}
+
+	return membership, nil
+}
+
+// NamesMessage represents a parsed NAMES reply (353).
+//
+// Twitch splits the chatters of a channel over multiple replies, the last one is followed by a 366.
+type NamesMessage struct {
+	Channel string
+
+	// Users are the login names of the chatters in this reply.
+	Users []string
+
+	Raw *Message
+}
+
+// ParseNames parses a NAMES reply (353).
+func ParseNames(message *Message) (*NamesMessage, error) {
+	names := &NamesMessage{
+		Channel: messageChannel(message),
+		Raw:     message,
+	}
+
+	if len(message.Params) > 3 {
+		names.Users = strings.Fields(message.Params[3])
+	}
+
+	return names, nil
+}
+
+// messageChannel returns the channel of message or an empty string if it is not about a channel.
+//
+// The channel is the first param of most messages, the numeric replies start with the nick instead
+// and only the NAMES replies are about a channel.
+func messageChannel(message *Message) string {
+	index := 0
+
+	switch message.Command {
+	case "353":
+		index = 2
+	case "366":
+		index = 1
+	default:
+		if isNumeric(message.Command) {
+			return ""
+		}
+	}
+
+	if len(message.Params) <= index {
+		return ""
+	}
+
+	return strings.TrimPrefix(message.Params[index], "#")
+}
+
+// presence holds the chatters of a channel.
+type presence struct {
+	chatters map[string]struct{}
+
+	// listing is set while twitch sends the NAMES replies of the channel.
+	listing bool
+
+	// last is the last JOIN or PART and changed reports whether it changed the chatters.
+	last    *Message
+	changed bool
+}
+
+// updatePresence applies a JOIN, PART, 353 or 366 to the chatters of channel.
+//
+// A JOIN of a present user or a PART of an absent user does not change the chatters,
+// so these duplicates can be dropped by the handlers.
+func (c *Connection) updatePresence(channel string, message *Message) {
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+
+	if c.presences == nil {
+		c.presences = make(map[string]*presence)
+	}
+
+	p, ok := c.presences[channel]
+	if !ok {
+		p = &presence{chatters: make(map[string]struct{})}
+		c.presences[channel] = p
+	}
+
+	switch message.Command {
+	case "JOIN", "PART":
+		membership, err := ParseMembership(message)
+		if err != nil {
+			return
+		}
+
+		_, present := p.chatters[membership.User]
+
+		if message.Command == "JOIN" {
+			p.chatters[membership.User] = struct{}{}
+		} else {
+			delete(p.chatters, membership.User)
+		}
+
+		p.last = message
+		p.changed = present != (message.Command == "JOIN")
+
+	case "353":
+		names, err := ParseNames(message)
+		if err != nil {
+			return
+		}
+
+		// A new NAMES list replaces the chatters, for example after a reconnect.
+		if !p.listing {
+			p.listing = true
+			p.chatters = make(map[string]struct{})
+		}
+
+		for _, user := range names.Users {
+			p.chatters[user] = struct{}{}
+		}
+
+	case "366":
+		p.listing = false
+	}
+}
+
+// membershipChanged reports whether the JOIN or PART in message changed the chatters of channel.
+// It returns true if the connection did not track the message.
+func (c *Connection) membershipChanged(channel string, message *Message) bool {
+	if c == nil {
+		return true
+	}
+
+	c.stateLock.RLock()
+	defer c.stateLock.RUnlock()
+
+	p, ok := c.presences[channel]
+	if !ok || p.last != message {
+		return true
+	}
+
+	return p.changed
+}
+
+// forgetPresence removes the chatters of channel.
+func (c *Connection) forgetPresence(channel string) {
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+
+	delete(c.presences, channel)
+}
+
+// Chatters returns the login names of the users in a joined channel, sorted by name.
+//
+// The chatters are only known if Config.CaptureMembership is set. Twitch only sends
+// the NAMES list for small channels and sends JOIN and PART messages in batches,
+// so the list can be incomplete and lag behind.
+func (c *Connection) Chatters(channel string) []string {
+	channel = strings.ToLower(channel)
+
+	c.stateLock.RLock()
+	defer c.stateLock.RUnlock()
+
+	p, ok := c.presences[channel]
+	if !ok {
+		return nil
+	}
+
+	chatters := make([]string, 0, len(p.chatters))
+	for user := range p.chatters {
+		chatters = append(chatters, user)
+	}
+
+	sort.Strings(chatters)
+
+	return chatters
+}
+
+// ChatterCount returns the number of users in a joined channel.
+func (c *Connection) ChatterCount(channel string) int {
+	channel = strings.ToLower(channel)
+
+	c.stateLock.RLock()
+	defer c.stateLock.RUnlock()
+
+	p, ok := c.presences[channel]
+	if !ok {
+		return 0
+	}
+
+	return len(p.chatters)
+}
+
+// isNumeric reports whether command is a numeric reply.
+func isNumeric(command string) bool {
+	if len(command) != 3 {
+		return false
+	}
+
+	for _, r := range command {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
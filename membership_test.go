@@ -0,0 +1,63 @@
+package twitchirc
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestConnection_Chatters(t *testing.T) {
+	var joins, parts []string
+	var names []*NamesMessage
+
+	conn := &Connection{
+		config:      &Config{},
+		handlerLock: &sync.RWMutex{},
+		ircHandler:  &IRCHandler{},
+		channelHandler: map[string]Handler{
+			"lirik": &ChannelHandler{
+				OnUserJoin: func(_ *Connection, m *MembershipMessage) { joins = append(joins, m.User) },
+				OnUserPart: func(_ *Connection, m *MembershipMessage) { parts = append(parts, m.User) },
+				OnNames:    func(_ *Connection, m *NamesMessage) { names = append(names, m) },
+			},
+		},
+	}
+
+	lines := []string{
+		":julezdev.tmi.twitch.tv 353 julezdev = #lirik :julezdev shaymin_fakezz",
+		":julezdev.tmi.twitch.tv 353 julezdev = #lirik :lirik",
+		":julezdev.tmi.twitch.tv 366 julezdev #lirik :End of /NAMES list",
+		":shaymin_fakezz!shaymin_fakezz@shaymin_fakezz.tmi.twitch.tv JOIN #lirik",
+		":bla!bla@bla.tmi.twitch.tv JOIN #lirik",
+		":bla!bla@bla.tmi.twitch.tv JOIN #lirik",
+		":lirik!lirik@lirik.tmi.twitch.tv PART #lirik",
+		":test!test@test.tmi.twitch.tv PART #lirik",
+	}
+
+	for _, line := range lines {
+		if err := conn.handleLine(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(names) != 2 || names[0].Channel != "lirik" || !reflect.DeepEqual(names[0].Users, []string{"julezdev", "shaymin_fakezz"}) {
+		t.Errorf("OnNames() got %v, want the two NAMES replies", names)
+	}
+
+	if want := []string{"bla"}; !reflect.DeepEqual(joins, want) {
+		t.Errorf("OnUserJoin() got %v, want %v", joins, want)
+	}
+
+	if want := []string{"lirik"}; !reflect.DeepEqual(parts, want) {
+		t.Errorf("OnUserPart() got %v, want %v", parts, want)
+	}
+
+	want := []string{"bla", "julezdev", "shaymin_fakezz"}
+	if got := conn.Chatters("Lirik"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Chatters() = %v, want %v", got, want)
+	}
+
+	if got := conn.ChatterCount("lirik"); got != len(want) {
+		t.Errorf("ChatterCount() = %v, want %v", got, len(want))
+	}
+}
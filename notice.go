@@ -0,0 +1,276 @@
+package twitchirc
+
+import (
+	"strings"
+)
+
+// NoticeID is the msg-id of a NOTICE.
+//
+// Unknown msg-ids are kept as they are, so a NoticeID can hold values which are not listed below.
+type NoticeID string
+
+// The msg-ids of NOTICE messages documented by twitch.
+const (
+	NoticeAlreadyBanned               NoticeID = "already_banned"
+	NoticeAlreadyEmoteOnlyOff         NoticeID = "already_emote_only_off"
+	NoticeAlreadyEmoteOnlyOn          NoticeID = "already_emote_only_on"
+	NoticeAlreadyFollowersOff         NoticeID = "already_followers_off"
+	NoticeAlreadyFollowersOn          NoticeID = "already_followers_on"
+	NoticeAlreadyR9KOff               NoticeID = "already_r9k_off"
+	NoticeAlreadyR9KOn                NoticeID = "already_r9k_on"
+	NoticeAlreadySlowOff              NoticeID = "already_slow_off"
+	NoticeAlreadySlowOn               NoticeID = "already_slow_on"
+	NoticeAlreadySubsOff              NoticeID = "already_subs_off"
+	NoticeAlreadySubsOn               NoticeID = "already_subs_on"
+	NoticeAutohostReceive             NoticeID = "autohost_receive"
+	NoticeBadBanAdmin                 NoticeID = "bad_ban_admin"
+	NoticeBadBanAnon                  NoticeID = "bad_ban_anon"
+	NoticeBadBanBroadcaster           NoticeID = "bad_ban_broadcaster"
+	NoticeBadBanMod                   NoticeID = "bad_ban_mod"
+	NoticeBadBanSelf                  NoticeID = "bad_ban_self"
+	NoticeBadBanStaff                 NoticeID = "bad_ban_staff"
+	NoticeBadCommercialError          NoticeID = "bad_commercial_error"
+	NoticeBadDeleteMessageBroadcaster NoticeID = "bad_delete_message_broadcaster"
+	NoticeBadDeleteMessageMod         NoticeID = "bad_delete_message_mod"
+	NoticeBadHostError                NoticeID = "bad_host_error"
+	NoticeBadHostHosting              NoticeID = "bad_host_hosting"
+	NoticeBadHostRateExceeded         NoticeID = "bad_host_rate_exceeded"
+	NoticeBadHostRejected             NoticeID = "bad_host_rejected"
+	NoticeBadHostSelf                 NoticeID = "bad_host_self"
+	NoticeBadModBanned                NoticeID = "bad_mod_banned"
+	NoticeBadModMod                   NoticeID = "bad_mod_mod"
+	NoticeBadSlowDuration             NoticeID = "bad_slow_duration"
+	NoticeBadTimeoutAdmin             NoticeID = "bad_timeout_admin"
+	NoticeBadTimeoutAnon              NoticeID = "bad_timeout_anon"
+	NoticeBadTimeoutBroadcaster       NoticeID = "bad_timeout_broadcaster"
+	NoticeBadTimeoutDuration          NoticeID = "bad_timeout_duration"
+	NoticeBadTimeoutMod               NoticeID = "bad_timeout_mod"
+	NoticeBadTimeoutSelf              NoticeID = "bad_timeout_self"
+	NoticeBadTimeoutStaff             NoticeID = "bad_timeout_staff"
+	NoticeBadUnbanNoBan               NoticeID = "bad_unban_no_ban"
+	NoticeBadUnhostError              NoticeID = "bad_unhost_error"
+	NoticeBadUnmodMod                 NoticeID = "bad_unmod_mod"
+	NoticeBadVIPGranteeBanned         NoticeID = "bad_vip_grantee_banned"
+	NoticeBadVIPGranteeAlreadyVIP     NoticeID = "bad_vip_grantee_already_vip"
+	NoticeBadVIPMaxVIPsReached        NoticeID = "bad_vip_max_vips_reached"
+	NoticeBadVIPAchievementIncomplete NoticeID = "bad_vip_achievement_incomplete"
+	NoticeBadUnVIPGranteeNotVIP       NoticeID = "bad_unvip_grantee_not_vip"
+	NoticeBanSuccess                  NoticeID = "ban_success"
+	NoticeCmdsAvailable               NoticeID = "cmds_available"
+	NoticeColorChanged                NoticeID = "color_changed"
+	NoticeCommercialSuccess           NoticeID = "commercial_success"
+	NoticeDeleteMessageSuccess        NoticeID = "delete_message_success"
+	NoticeDeleteStaffMessageSuccess   NoticeID = "delete_staff_message_success"
+	NoticeEmoteOnlyOff                NoticeID = "emote_only_off"
+	NoticeEmoteOnlyOn                 NoticeID = "emote_only_on"
+	NoticeFollowersOff                NoticeID = "followers_off"
+	NoticeFollowersOn                 NoticeID = "followers_on"
+	NoticeFollowersOnZero             NoticeID = "followers_on_zero"
+	NoticeHostOff                     NoticeID = "host_off"
+	NoticeHostOn                      NoticeID = "host_on"
+	NoticeHostReceive                 NoticeID = "host_receive"
+	NoticeHostReceiveNoCount          NoticeID = "host_receive_no_count"
+	NoticeHostTargetWentOffline       NoticeID = "host_target_went_offline"
+	NoticeHostsRemaining              NoticeID = "hosts_remaining"
+	NoticeInvalidUser                 NoticeID = "invalid_user"
+	NoticeModSuccess                  NoticeID = "mod_success"
+	NoticeMsgBanned                   NoticeID = "msg_banned"
+	NoticeMsgBadCharacters            NoticeID = "msg_bad_characters"
+	NoticeMsgChannelBlocked           NoticeID = "msg_channel_blocked"
+	NoticeMsgChannelSuspended         NoticeID = "msg_channel_suspended"
+	NoticeMsgDuplicate                NoticeID = "msg_duplicate"
+	NoticeMsgEmoteOnly                NoticeID = "msg_emoteonly"
+	NoticeMsgFacebook                 NoticeID = "msg_facebook"
+	NoticeMsgFollowersOnly            NoticeID = "msg_followersonly"
+	NoticeMsgFollowersOnlyFollowed    NoticeID = "msg_followersonly_followed"
+	NoticeMsgFollowersOnlyZero        NoticeID = "msg_followersonly_zero"
+	NoticeMsgR9K                      NoticeID = "msg_r9k"
+	NoticeMsgRateLimit                NoticeID = "msg_ratelimit"
+	NoticeMsgRejected                 NoticeID = "msg_rejected"
+	NoticeMsgRejectedMandatory        NoticeID = "msg_rejected_mandatory"
+	NoticeMsgRequiresVerifiedPhone    NoticeID = "msg_requires_verified_phone_number"
+	NoticeMsgSlowMode                 NoticeID = "msg_slowmode"
+	NoticeMsgSubsOnly                 NoticeID = "msg_subsonly"
+	NoticeMsgSuspended                NoticeID = "msg_suspended"
+	NoticeMsgTimedOut                 NoticeID = "msg_timedout"
+	NoticeMsgVerifiedEmail            NoticeID = "msg_verified_email"
+	NoticeNoHelp                      NoticeID = "no_help"
+	NoticeNoMods                      NoticeID = "no_mods"
+	NoticeNoVIPs                      NoticeID = "no_vips"
+	NoticeNotHosting                  NoticeID = "not_hosting"
+	NoticeNoPermission                NoticeID = "no_permission"
+	NoticeR9KOff                      NoticeID = "r9k_off"
+	NoticeR9KOn                       NoticeID = "r9k_on"
+	NoticeRaidErrorAlreadyRaiding     NoticeID = "raid_error_already_raiding"
+	NoticeRaidErrorForbidden          NoticeID = "raid_error_forbidden"
+	NoticeRaidErrorSelf               NoticeID = "raid_error_self"
+	NoticeRaidErrorTooManyViewers     NoticeID = "raid_error_too_many_viewers"
+	NoticeRaidErrorUnexpected         NoticeID = "raid_error_unexpected"
+	NoticeRaidNoticeMature            NoticeID = "raid_notice_mature"
+	NoticeRaidNoticeRestrictedChat    NoticeID = "raid_notice_restricted_chat"
+	NoticeRoomMods                    NoticeID = "room_mods"
+	NoticeSlowOff                     NoticeID = "slow_off"
+	NoticeSlowOn                      NoticeID = "slow_on"
+	NoticeSubsOff                     NoticeID = "subs_off"
+	NoticeSubsOn                      NoticeID = "subs_on"
+	NoticeTimeoutNoTimeout            NoticeID = "timeout_no_timeout"
+	NoticeTimeoutSuccess              NoticeID = "timeout_success"
+	NoticeTOSBan                      NoticeID = "tos_ban"
+	NoticeTurboOnlyColor              NoticeID = "turbo_only_color"
+	NoticeUnavailableCommand          NoticeID = "unavailable_command"
+	NoticeUnbanSuccess                NoticeID = "unban_success"
+	NoticeUnmodSuccess                NoticeID = "unmod_success"
+	NoticeUnraidErrorNoActiveRaid     NoticeID = "unraid_error_no_active_raid"
+	NoticeUnraidErrorUnexpected       NoticeID = "unraid_error_unexpected"
+	NoticeUnraidSuccess               NoticeID = "unraid_success"
+	NoticeUnrecognizedCmd             NoticeID = "unrecognized_cmd"
+	NoticeUntimeoutBanned             NoticeID = "untimeout_banned"
+	NoticeUntimeoutSuccess            NoticeID = "untimeout_success"
+	NoticeUnVIPSuccess                NoticeID = "unvip_success"
+	NoticeVIPSuccess                  NoticeID = "vip_success"
+	NoticeVIPsSuccess                 NoticeID = "vips_success"
+	NoticeWhisperBanned               NoticeID = "whisper_banned"
+	NoticeWhisperBannedRecipient      NoticeID = "whisper_banned_recipient"
+	NoticeWhisperInvalidLogin         NoticeID = "whisper_invalid_login"
+	NoticeWhisperInvalidSelf          NoticeID = "whisper_invalid_self"
+	NoticeWhisperLimitPerMin          NoticeID = "whisper_limit_per_min"
+	NoticeWhisperLimitPerSec          NoticeID = "whisper_limit_per_sec"
+	NoticeWhisperRestricted           NoticeID = "whisper_restricted"
+	NoticeWhisperRestrictedRecipient  NoticeID = "whisper_restricted_recipient"
+)
+
+// Twitch sends the login failures without a msg-id, these ids are set by the parser instead.
+const (
+	// NoticeImproperlyFormattedAuth is set if the password was not a valid oauth token.
+	NoticeImproperlyFormattedAuth NoticeID = "improperly_formatted_auth"
+
+	// NoticeLoginAuthenticationFailed is set if twitch rejected the oauth token.
+	NoticeLoginAuthenticationFailed NoticeID = "login_authentication_failed"
+
+	// NoticeUnknown is set if the notice has neither a msg-id nor a known text.
+	NoticeUnknown NoticeID = ""
+)
+
+// noticeTexts maps the text of NOTICE messages which are sent without a msg-id to an id.
+//
+// Twitch sends the login failures without tags, so they can only be recognized by their text.
+var noticeTexts = map[string]NoticeID{
+	"Login authentication failed": NoticeLoginAuthenticationFailed,
+	"Improperly formatted auth":   NoticeImproperlyFormattedAuth,
+}
+
+// NoticeKind classifies a NOTICE by its effect on the connection.
+type NoticeKind int
+
+const (
+	// NoticeInformational is a notice which only informs about an event or the result of a command.
+	NoticeInformational NoticeKind = iota
+
+	// NoticeSendFailure is a notice which reports that a message or whisper was not delivered.
+	NoticeSendFailure
+
+	// NoticeFatal is a notice after which the connection or the channel can't be used anymore.
+	NoticeFatal
+)
+
+func (k NoticeKind) String() string {
+	switch k {
+	case NoticeSendFailure:
+		return "send failure"
+	case NoticeFatal:
+		return "fatal"
+	}
+
+	return "informational"
+}
+
+// noticeKinds holds the kind of all notices which are not informational.
+var noticeKinds = map[NoticeID]NoticeKind{
+	NoticeMsgBadCharacters:         NoticeSendFailure,
+	NoticeMsgDuplicate:             NoticeSendFailure,
+	NoticeMsgEmoteOnly:             NoticeSendFailure,
+	NoticeMsgFacebook:              NoticeSendFailure,
+	NoticeMsgFollowersOnly:         NoticeSendFailure,
+	NoticeMsgFollowersOnlyFollowed: NoticeSendFailure,
+	NoticeMsgFollowersOnlyZero:     NoticeSendFailure,
+	NoticeMsgR9K:                   NoticeSendFailure,
+	NoticeMsgRateLimit:             NoticeSendFailure,
+	NoticeMsgRejected:              NoticeSendFailure,
+	NoticeMsgRejectedMandatory:     NoticeSendFailure,
+	NoticeMsgRequiresVerifiedPhone: NoticeSendFailure,
+	NoticeMsgSlowMode:              NoticeSendFailure,
+	NoticeMsgSubsOnly:              NoticeSendFailure,
+	NoticeMsgSuspended:             NoticeSendFailure,
+	NoticeMsgTimedOut:              NoticeSendFailure,
+	NoticeMsgVerifiedEmail:         NoticeSendFailure,
+
+	NoticeWhisperBanned:              NoticeSendFailure,
+	NoticeWhisperBannedRecipient:     NoticeSendFailure,
+	NoticeWhisperInvalidLogin:        NoticeSendFailure,
+	NoticeWhisperInvalidSelf:         NoticeSendFailure,
+	NoticeWhisperLimitPerMin:         NoticeSendFailure,
+	NoticeWhisperLimitPerSec:         NoticeSendFailure,
+	NoticeWhisperRestricted:          NoticeSendFailure,
+	NoticeWhisperRestrictedRecipient: NoticeSendFailure,
+
+	NoticeMsgBanned:                 NoticeFatal,
+	NoticeMsgChannelBlocked:         NoticeFatal,
+	NoticeMsgChannelSuspended:       NoticeFatal,
+	NoticeTOSBan:                    NoticeFatal,
+	NoticeImproperlyFormattedAuth:   NoticeFatal,
+	NoticeLoginAuthenticationFailed: NoticeFatal,
+}
+
+// Kind returns the kind of the notice. Unknown notices are informational.
+func (id NoticeID) Kind() NoticeKind {
+	return noticeKinds[id]
+}
+
+// IsInformational reports whether the notice only informs about an event or the result of a command.
+func (id NoticeID) IsInformational() bool {
+	return id.Kind() == NoticeInformational
+}
+
+// IsSendFailure reports whether the notice reports that a message or whisper was not delivered.
+func (id NoticeID) IsSendFailure() bool {
+	return id.Kind() == NoticeSendFailure
+}
+
+// IsFatal reports whether the connection or the channel can't be used anymore after the notice.
+func (id NoticeID) IsFatal() bool {
+	return id.Kind() == NoticeFatal
+}
+
+// NoticeMessage represents a parsed NOTICE.
+type NoticeMessage struct {
+	ID NoticeID
+
+	// Channel is the channel the notice is about, it is empty if the notice is about the connection.
+	Channel string
+	Text    string
+
+	Raw *Message
+}
+
+// ParseNotice parses a NOTICE.
+func ParseNotice(message *Message) (*NoticeMessage, error) {
+	notice := &NoticeMessage{
+		Raw: message,
+	}
+
+	if len(message.Params) > 0 && message.Params[0] != "*" {
+		notice.Channel = strings.TrimPrefix(message.Params[0], "#")
+	}
+
+	if len(message.Params) > 1 {
+		notice.Text = message.Params[1]
+	}
+
+	if msgID, ok := message.GetTag("msg-id"); ok {
+		notice.ID = NoticeID(msgID)
+	} else {
+		notice.ID = noticeTexts[notice.Text]
+	}
+
+	return notice, nil
+}
@@ -0,0 +1,111 @@
+package twitchirc
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+const (
+	slowModeNotice    = "@msg-id=msg_slowmode :tmi.twitch.tv NOTICE #julezdev :This room is in slow mode and you are sending messages too quickly. You will be able to talk again in 5 seconds."
+	loginFailedNotice = ":tmi.twitch.tv NOTICE * :Login authentication failed"
+)
+
+func TestParseNotice(t *testing.T) {
+	tests := []struct {
+		name    string
+		message *Message
+		want    *NoticeMessage
+		kind    NoticeKind
+	}{
+		{
+			name:    "slow-mode",
+			message: mustParseMessage(slowModeNotice),
+			want: &NoticeMessage{
+				ID:      NoticeMsgSlowMode,
+				Channel: "julezdev",
+				Text:    "This room is in slow mode and you are sending messages too quickly. You will be able to talk again in 5 seconds.",
+			},
+			kind: NoticeSendFailure,
+		},
+		{
+			name:    "login-failed",
+			message: mustParseMessage(loginFailedNotice),
+			want: &NoticeMessage{
+				ID:   NoticeLoginAuthenticationFailed,
+				Text: "Login authentication failed",
+			},
+			kind: NoticeFatal,
+		},
+		{
+			name:    "informational",
+			message: mustParseMessage("@msg-id=slow_on :tmi.twitch.tv NOTICE #julezdev :This room is now in slow mode."),
+			want: &NoticeMessage{
+				ID:      NoticeSlowOn,
+				Channel: "julezdev",
+				Text:    "This room is now in slow mode.",
+			},
+			kind: NoticeInformational,
+		},
+		{
+			name:    "unknown",
+			message: mustParseMessage("@msg-id=something_new :tmi.twitch.tv NOTICE #julezdev :Something new."),
+			want: &NoticeMessage{
+				ID:      NoticeID("something_new"),
+				Channel: "julezdev",
+				Text:    "Something new.",
+			},
+			kind: NoticeInformational,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.want.Raw = tt.message
+
+			got, err := ParseNotice(tt.message)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseNotice() = %v, want %v", got, tt.want)
+			}
+
+			if got.ID.Kind() != tt.kind {
+				t.Errorf("NoticeID.Kind() = %v, want %v", got.ID.Kind(), tt.kind)
+			}
+		})
+	}
+}
+
+func TestConnection_handleLine_notice(t *testing.T) {
+	var global, channel []*NoticeMessage
+
+	conn := &Connection{
+		config:      &Config{},
+		handlerLock: &sync.RWMutex{},
+		ircHandler: &IRCHandler{
+			OnNotice: func(_ *Connection, notice *NoticeMessage) { global = append(global, notice) },
+		},
+		channelHandler: map[string]Handler{
+			"julezdev": &ChannelHandler{
+				OnNotice: func(_ *Connection, notice *NoticeMessage) { channel = append(channel, notice) },
+			},
+		},
+	}
+
+	for _, line := range []string{loginFailedNotice, slowModeNotice} {
+		if err := conn.handleLine(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(global) != 1 || !global[0].ID.IsFatal() {
+		t.Errorf("IRCHandler.OnNotice() got %v, want the login failure", global)
+	}
+
+	if len(channel) != 1 || !channel[0].ID.IsSendFailure() {
+		t.Errorf("ChannelHandler.OnNotice() got %v, want the slow mode notice", channel)
+	}
+}
@@ -0,0 +1,71 @@
+package twitchirc
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// OutgoingMessage is a PRIVMSG with IRCv3 client tags which can be sent with Connection.Send.
+type OutgoingMessage struct {
+	Channel string
+	Text    string
+	Tags    Tags
+}
+
+// NewMessage returns an OutgoingMessage without tags for channel.
+func NewMessage(channel, text string) *OutgoingMessage {
+	return &OutgoingMessage{
+		Channel: channel,
+		Text:    text,
+		Tags:    Tags{},
+	}
+}
+
+// WithTag sets the tag key to value.
+func (m *OutgoingMessage) WithTag(key, value string) *OutgoingMessage {
+	if m.Tags == nil {
+		m.Tags = Tags{}
+	}
+
+	m.Tags[key] = TagValue(value)
+
+	return m
+}
+
+// ReplyTo makes the message a reply to the message with the id parentID.
+func (m *OutgoingMessage) ReplyTo(parentID string) *OutgoingMessage {
+	return m.WithTag("reply-parent-msg-id", parentID)
+}
+
+// WithNonce sets the client-nonce tag, twitch sends it back in the PRIVMSG of the message.
+func (m *OutgoingMessage) WithNonce(nonce string) *OutgoingMessage {
+	return m.WithTag("client-nonce", nonce)
+}
+
+// Message returns the message which gets sent to twitch.
+func (m *OutgoingMessage) Message() *Message {
+	return &Message{
+		Tags:    m.Tags,
+		Command: "PRIVMSG",
+		Params:  []string{"#" + strings.ToLower(strings.TrimPrefix(m.Channel, "#")), m.Text},
+	}
+}
+
+// Send sends the message with its tags.
+//
+// Like Say the message is limited by Config.RateLimit.
+func (c *Connection) Send(message *OutgoingMessage) error {
+	line, err := message.Message().MarshalText()
+
+	if err != nil {
+		return errors.Wrapf(err, "connection.Send: could not encode message to %s", message.Channel)
+	}
+
+	return c.Write(string(line))
+}
+
+// Reply sends text as a reply to parent in the channel of parent.
+func (c *Connection) Reply(parent *PrivateMessage, text string) error {
+	return c.Send(NewMessage(parent.Channel, text).ReplyTo(parent.ID))
+}
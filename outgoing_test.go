@@ -0,0 +1,39 @@
+package twitchirc
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestConnection_Send(t *testing.T) {
+	srv := newFakeServer()
+
+	client := NewClient("julezdev", "oauth:123", &Config{CaptureTags: true})
+	client.dialer = srv.dial
+
+	conn, err := client.Connect(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	if err := conn.Send(NewMessage("#Lirik", "hello world").WithNonce("c0ffee")); err != nil {
+		t.Fatal(err)
+	}
+
+	srv.expect(t, "@client-nonce=c0ffee PRIVMSG #lirik :hello world")
+
+	parent := &PrivateMessage{ID: "5bb550d4-bd15-4a96-9de2-c0298b2d01a9", Channel: "julezdev"}
+
+	if err := conn.Reply(parent, "hi"); err != nil {
+		t.Fatal(err)
+	}
+
+	srv.expect(t, "@reply-parent-msg-id=5bb550d4-bd15-4a96-9de2-c0298b2d01a9 PRIVMSG #julezdev :hi")
+
+	if err := conn.Send(NewMessage("julezdev", "two\r\nlines")); errors.Cause(err) != ErrInvalidParam {
+		t.Errorf("Send() error = %v, want %v", err, ErrInvalidParam)
+	}
+}
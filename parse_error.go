@@ -0,0 +1,225 @@
+package twitchirc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ParseMode decides what happens to messages with malformed fields.
+type ParseMode int
+
+const (
+	// ParseLenient skips malformed fields and drops lines which can't be parsed at all.
+	ParseLenient ParseMode = iota
+
+	// ParseStrict drops every message with a malformed field.
+	// The *ParseError is passed to the irc handler if it implements ParseErrorHandler.
+	ParseStrict
+)
+
+var (
+	// ErrMissingParam is reported if a message has less params than its command requires.
+	ErrMissingParam = errors.New("twitchirc: missing param")
+
+	// ErrInvalidField is reported if a tag or param has an invalid format.
+	ErrInvalidField = errors.New("twitchirc: invalid field")
+)
+
+// ParseError is returned if a line or one of its fields could not be parsed.
+type ParseError struct {
+	// Command is the command of the message, it is empty if the line could not be split into a message.
+	Command string
+
+	// Field is the tag or part of the message which could not be parsed, like badges or params.
+	Field string
+
+	// Offset is the byte offset of the malformed field in Raw.
+	Offset int
+
+	Raw string
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("twitchirc: could not parse %s of %s at offset %d: %v", e.Field, e.Command, e.Offset, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Cause returns the underlying error for github.com/pkg/errors.
+func (e *ParseError) Cause() error {
+	return e.Err
+}
+
+// newParamError returns a ParseError for a message which has less than n params.
+func newParamError(message *Message, n int) *ParseError {
+	return &ParseError{
+		Command: message.Command,
+		Field:   "params",
+		Offset:  len(message.Message),
+		Raw:     message.Message,
+		Err:     errors.Wrapf(ErrMissingParam, "want %d params, got %d", n, len(message.Params)),
+	}
+}
+
+// newTagError returns a ParseError for the tag key, index is the position of the error in the tag value.
+func newTagError(message *Message, key string, index int, err error) *ParseError {
+	offset := -1
+
+	for _, sep := range []string{"@", ";"} {
+		if i := strings.Index(message.Message, sep+key+"="); i >= 0 {
+			offset = i + len(sep) + len(key) + 1 + index
+			break
+		}
+	}
+
+	return &ParseError{
+		Command: message.Command,
+		Field:   key,
+		Offset:  offset,
+		Raw:     message.Message,
+		Err:     err,
+	}
+}
+
+// parseFailure returns err unless it is caused by a *ParseError.
+//
+// A message which can't be parsed is skipped instead of stopping Run.
+// In strict mode the *ParseError is passed to the irc handler.
+func (c *Connection) parseFailure(err error) error {
+	var parseErr *ParseError
+
+	if !errors.As(err, &parseErr) {
+		return err
+	}
+
+	if c.config.ParseMode == ParseStrict {
+		c.emitParseError(parseErr)
+	}
+
+	return nil
+}
+
+// emitParseError passes the error to the irc handler if it implements ParseErrorHandler.
+func (c *Connection) emitParseError(parseErr *ParseError) {
+	if h, ok := c.ircHandler.(ParseErrorHandler); ok {
+		h.HandleParseError(c, parseErr)
+	}
+}
+
+// requiredParams is the number of params the typed parsers need for a command.
+var requiredParams = map[string]int{
+	"PRIVMSG": 2,
+	"WHISPER": 2,
+}
+
+// intTags are the tags which get parsed as number.
+var intTags = []string{
+	"bits", "ban-duration", "mod", "subscriber", "turbo", "vip", "tmi-sent-ts",
+	"emote-only", "r9k", "subs-only", "followers-only", "slow",
+	"msg-param-cumulative-months", "msg-param-streak-months", "msg-param-should-share-streak",
+	"msg-param-months", "msg-param-gift-months", "msg-param-sender-count",
+	"msg-param-mass-gift-count", "msg-param-promo-gift-total", "msg-param-viewerCount",
+	"msg-param-threshold",
+}
+
+// ValidateMessage checks the params and the known tags of message.
+//
+// It returns a *ParseError for the first field the typed parsers would skip.
+// The typed parsers are lenient, use ValidateMessage before them to parse strictly.
+func ValidateMessage(message *Message) error {
+	if n, ok := requiredParams[message.Command]; ok && len(message.Params) < n {
+		return newParamError(message, n)
+	}
+
+	for _, key := range []string{"badges", "badge-info"} {
+		if value, ok := message.GetTag(key); ok && value != "" {
+			if index, err := validateBadges(value); err != nil {
+				return newTagError(message, key, index, err)
+			}
+		}
+	}
+
+	if value, ok := message.GetTag("emotes"); ok && value != "" {
+		var text string
+		if len(message.Params) > 1 {
+			text = message.Params[len(message.Params)-1]
+		}
+
+		if index, err := validateEmotes(value, text); err != nil {
+			return newTagError(message, "emotes", index, err)
+		}
+	}
+
+	for _, key := range intTags {
+		if value, ok := message.GetTag(key); ok && value != "" {
+			if _, err := strconv.Atoi(value); err != nil {
+				return newTagError(message, key, 0, errors.Wrapf(ErrInvalidField, "%q is not a number", value))
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateBadges checks that every badge has a name and a version.
+// It returns the position of the first malformed badge.
+func validateBadges(badgesTag string) (int, error) {
+	index := 0
+
+	for _, badge := range strings.Split(badgesTag, ",") {
+		pair := strings.SplitN(badge, "/", 2)
+
+		if len(pair) != 2 || pair[0] == "" {
+			return index, errors.Wrapf(ErrInvalidField, "badge %q has no version", badge)
+		}
+
+		index += len(badge) + 1
+	}
+
+	return 0, nil
+}
+
+// validateEmotes checks that every emote has an id and valid positions inside of text.
+// It returns the position of the first malformed emote.
+func validateEmotes(emoteString, text string) (int, error) {
+	length := len([]rune(text))
+	index := 0
+
+	for _, emote := range strings.Split(emoteString, "/") {
+		split := strings.SplitN(emote, ":", 2)
+
+		if len(split) != 2 || split[0] == "" {
+			return index, errors.Wrapf(ErrInvalidField, "emote %q has no positions", emote)
+		}
+
+		for _, rawPosition := range strings.Split(split[1], ",") {
+			pair := strings.SplitN(rawPosition, "-", 2)
+
+			if len(pair) != 2 {
+				return index, errors.Wrapf(ErrInvalidField, "emote position %q is not a range", rawPosition)
+			}
+
+			start, startErr := strconv.Atoi(pair[0])
+			end, endErr := strconv.Atoi(pair[1])
+
+			if startErr != nil || endErr != nil {
+				return index, errors.Wrapf(ErrInvalidField, "emote position %q is not a range", rawPosition)
+			}
+
+			if start < 0 || start > end || end >= length {
+				return index, errors.Wrapf(ErrInvalidField, "emote position %q is outside of the %d runes of the text", rawPosition, length)
+			}
+		}
+
+		index += len(emote) + 1
+	}
+
+	return 0, nil
+}
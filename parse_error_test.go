@@ -0,0 +1,254 @@
+package twitchirc
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestParseMessage_ParseError(t *testing.T) {
+	tests := []struct {
+		name   string
+		line   string
+		field  string
+		offset int
+		want   error
+	}{
+		{name: "empty", line: "\r\n", field: "line", offset: 0, want: ErrZeroLengthMessage},
+		{name: "only-tags", line: "@badges=vip/1", field: "tags", offset: 0, want: ErrMissingDataAfterTags},
+		{name: "nothing-after-tags", line: "@badges=vip/1 ", field: "tags", offset: 0, want: ErrMissingDataAfterTags},
+		{name: "nothing-after-prefix", line: "@a=b :tmi.twitch.tv ", field: "prefix", offset: 5, want: ErrMissingDataAfterPrefix},
+		{name: "no-command", line: ":tmi.twitch.tv  :text", field: "command", offset: 15, want: ErrMissingCommand},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseMessage(tt.line)
+
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("ParseMessage() error = %v, want *ParseError", err)
+			}
+
+			if parseErr.Field != tt.field || parseErr.Offset != tt.offset {
+				t.Errorf("ParseError field = %q at %d, want %q at %d", parseErr.Field, parseErr.Offset, tt.field, tt.offset)
+			}
+
+			if !errors.Is(err, tt.want) {
+				t.Errorf("ParseMessage() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePrivateMessage_MissingParams(t *testing.T) {
+	msg := mustParseMessage("@badges=vip/1;emotes=25:0-4 :julezdev!julezdev@julezdev.tmi.twitch.tv PRIVMSG #julezdev")
+
+	_, err := ParsePrivateMessage(msg)
+
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("ParsePrivateMessage() error = %v, want *ParseError", err)
+	}
+
+	want := &ParseError{
+		Command: "PRIVMSG",
+		Field:   "params",
+		Offset:  len(msg.Message),
+		Raw:     msg.Message,
+	}
+
+	parseErr.Err = nil
+	if !reflect.DeepEqual(parseErr, want) {
+		t.Errorf("ParsePrivateMessage() error = %#v, want %#v", parseErr, want)
+	}
+
+	if _, err := ParseWhisper(mustParseMessage(":julezdev!julezdev@julezdev.tmi.twitch.tv WHISPER julezdev")); errors.Cause(err) != ErrMissingParam {
+		t.Errorf("ParseWhisper() error = %v, want %v", err, ErrMissingParam)
+	}
+}
+
+func TestParseUser_MalformedBadges(t *testing.T) {
+	msg := mustParseMessage("@badges=vip,moderator/1,/2;badge-info=subscriber :julezdev!julezdev@julezdev.tmi.twitch.tv PRIVMSG #julezdev :Hello")
+
+	got, err := ParsePrivateMessage(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := map[string]int{"moderator": 1}; !reflect.DeepEqual(got.User.Badges, want) {
+		t.Errorf("Badges = %v, want %v", got.User.Badges, want)
+	}
+
+	if len(got.User.BadgeInfo) != 0 {
+		t.Errorf("BadgeInfo = %v, want empty", got.User.BadgeInfo)
+	}
+}
+
+func TestValidateMessage(t *testing.T) {
+	tests := []struct {
+		name   string
+		line   string
+		field  string
+		offset int
+		want   error
+	}{
+		{
+			name: "valid",
+			line: "@badges=vip/1,moderator/1;bits=100;emotes=25:0-4 :julezdev!julezdev@julezdev.tmi.twitch.tv PRIVMSG #julezdev :Kappa Cheer100",
+		},
+		{
+			name:   "badge-without-version",
+			line:   "@badges=vip/1,moderator :julezdev!julezdev@julezdev.tmi.twitch.tv PRIVMSG #julezdev :Hello",
+			field:  "badges",
+			offset: 14,
+			want:   ErrInvalidField,
+		},
+		{
+			name:   "emote-outside-of-text",
+			line:   "@emotes=25:0-4/1902:10-14 :julezdev!julezdev@julezdev.tmi.twitch.tv PRIVMSG #julezdev :Kappa",
+			field:  "emotes",
+			offset: 15,
+			want:   ErrInvalidField,
+		},
+		{
+			name:   "emote-far-outside-of-text",
+			line:   "@emotes=25:0-9999 :julezdev!julezdev@julezdev.tmi.twitch.tv PRIVMSG #julezdev :Kappa",
+			field:  "emotes",
+			offset: 8,
+			want:   ErrInvalidField,
+		},
+		{
+			name:   "emote-not-a-range",
+			line:   "@emotes=25:0-4,x-4 :julezdev!julezdev@julezdev.tmi.twitch.tv PRIVMSG #julezdev :Kappa",
+			field:  "emotes",
+			offset: 8,
+			want:   ErrInvalidField,
+		},
+		{
+			name:   "bits-not-a-number",
+			line:   "@bits=many :julezdev!julezdev@julezdev.tmi.twitch.tv PRIVMSG #julezdev :Cheer100",
+			field:  "bits",
+			offset: 6,
+			want:   ErrInvalidField,
+		},
+		{
+			name:   "missing-text",
+			line:   ":julezdev!julezdev@julezdev.tmi.twitch.tv PRIVMSG #julezdev",
+			field:  "params",
+			offset: 59,
+			want:   ErrMissingParam,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateMessage(mustParseMessage(tt.line))
+
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("ValidateMessage() error = %v, want nil", err)
+				}
+				return
+			}
+
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("ValidateMessage() error = %v, want *ParseError", err)
+			}
+
+			if parseErr.Field != tt.field || parseErr.Offset != tt.offset {
+				t.Errorf("ParseError field = %q at %d, want %q at %d", parseErr.Field, parseErr.Offset, tt.field, tt.offset)
+			}
+
+			if errors.Cause(err) != tt.want {
+				t.Errorf("ValidateMessage() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestConnection_ParseMode(t *testing.T) {
+	lines := []string{
+		"@badges=vip :julezdev!julezdev@julezdev.tmi.twitch.tv PRIVMSG #julezdev :Hello",
+		":julezdev!julezdev@julezdev.tmi.twitch.tv PRIVMSG #julezdev",
+		"@badges=vip/1 ",
+	}
+
+	newConnection := func(mode ParseMode) (*Connection, *[]*PrivateMessage, *[]*ParseError) {
+		var (
+			received []*PrivateMessage
+			reported []*ParseError
+		)
+
+		handler := &ChannelHandler{
+			OnPrivateMessage: func(conn *Connection, msg *PrivateMessage) {
+				received = append(received, msg)
+			},
+		}
+
+		return &Connection{
+			config:      &Config{ParseMode: mode},
+			handlerLock: &sync.RWMutex{},
+			ircHandler: &IRCHandler{
+				OnParseError: func(conn *Connection, parseErr *ParseError) {
+					reported = append(reported, parseErr)
+				},
+			},
+			channelHandler: map[string]Handler{"julezdev": handler},
+		}, &received, &reported
+	}
+
+	t.Run("lenient", func(t *testing.T) {
+		conn, received, reported := newConnection(ParseLenient)
+
+		for _, line := range lines {
+			if err := conn.handleLine(line); err != nil {
+				t.Errorf("handleLine(%q) error = %v, want nil", line, err)
+			}
+		}
+
+		if len(*received) != 1 || (*received)[0].Text != "Hello" {
+			t.Errorf("received %v, want only the message with the malformed badge", *received)
+		}
+
+		if len(*reported) != 0 {
+			t.Errorf("reported %v, want none", *reported)
+		}
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		conn, received, reported := newConnection(ParseStrict)
+
+		for _, line := range lines {
+			if err := conn.handleLine(line); err != nil {
+				t.Errorf("handleLine(%q) error = %v, want nil", line, err)
+			}
+		}
+
+		if len(*received) != 0 {
+			t.Errorf("received %v, want none", *received)
+		}
+
+		if len(*reported) != len(lines) {
+			t.Fatalf("reported %d errors, want %d", len(*reported), len(lines))
+		}
+
+		for i, field := range []string{"badges", "params", "tags"} {
+			if got := (*reported)[i].Field; got != field {
+				t.Errorf("reported error %d has field %q, want %q", i, got, field)
+			}
+		}
+
+		// the connection keeps handling messages after a malformed one
+		if err := conn.handleLine("@badges=vip/1 :julezdev!julezdev@julezdev.tmi.twitch.tv PRIVMSG #julezdev :Hello"); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(*received) != 1 {
+			t.Errorf("received %d messages, want 1", len(*received))
+		}
+	})
+}
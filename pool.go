@@ -0,0 +1,457 @@
+package twitchirc
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// defaultPoolChannels is the default number of channels per connection of a pool.
+const defaultPoolChannels = 50
+
+// poolChannel is a channel joined by a pool.
+type poolChannel struct {
+	conn    *Connection
+	handler Handler
+}
+
+// Pool distributes channels over multiple connections of a client.
+//
+// The pool opens a new connection once all connections hold the maximum number of channels.
+// If a connection dies its channels get moved to the other connections.
+// The pool hides the single connections, channels are joined, departed and written to
+// through the pool.
+type Pool struct {
+	client      *Client
+	ircHandler  Handler
+	maxChannels int
+
+	lock     sync.Mutex
+	conns    []*Connection
+	channels map[string]*poolChannel
+	ctx      context.Context
+	errCh    chan error
+	done     chan struct{}
+	closed   bool
+	wg       sync.WaitGroup
+
+	// reserved counts the channels which running Joins assigned to a connection but did not record yet.
+	reserved map[*Connection]int
+
+	// dialing is set while a new connection is dialed, dialed is signaled once the dial is done.
+	dialing bool
+	dialed  *sync.Cond
+}
+
+// NewPool returns a new pool which creates its connections with client.
+//
+// The ircHandler is used for all connections of the pool.
+// If the ircHandler is nil an empty twitchirc.IRCHandler will be used.
+//
+// maxChannels is the maximum number of channels per connection.
+// If maxChannels is zero or less a connection holds up to 50 channels.
+func NewPool(client *Client, ircHandler Handler, maxChannels int) *Pool {
+	if ircHandler == nil {
+		ircHandler = &IRCHandler{}
+	}
+
+	if maxChannels <= 0 {
+		maxChannels = defaultPoolChannels
+	}
+
+	p := &Pool{
+		client:      client,
+		ircHandler:  ircHandler,
+		maxChannels: maxChannels,
+		channels:    make(map[string]*poolChannel),
+		reserved:    make(map[*Connection]int),
+		errCh:       make(chan error, 1),
+		done:        make(chan struct{}),
+	}
+
+	p.dialed = sync.NewCond(&p.lock)
+
+	return p
+}
+
+// Run runs all connections of the pool.
+//
+// Run blocks the current goroutine until ctx is canceled, the pool gets closed
+// or the channels of a dead connection could not be moved to another connection.
+// Connections which are opened while Run is running get started automatically.
+//
+// If this method is canceled it will automatically close the pool.
+func (p *Pool) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+
+	defer func() {
+		cancel()
+		p.Close()
+		p.wg.Wait()
+	}()
+
+	p.lock.Lock()
+
+	if p.closed {
+		p.lock.Unlock()
+		return nil
+	}
+
+	p.ctx = ctx
+	for _, conn := range p.conns {
+		p.start(conn)
+	}
+
+	p.lock.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-p.done:
+		return nil
+	case err := <-p.errCh:
+		return err
+	}
+}
+
+// start runs conn in a new goroutine.
+// The caller must hold the lock of the pool.
+func (p *Pool) start(conn *Connection) {
+	p.wg.Add(1)
+
+	go func() {
+		defer p.wg.Done()
+
+		conn.Run(p.ctx)
+
+		if p.ctx.Err() != nil {
+			return
+		}
+
+		if err := p.move(conn); err != nil {
+			select {
+			case p.errCh <- err:
+			default:
+			}
+		}
+	}()
+}
+
+// move removes conn from the pool and joins its channels on the remaining connections.
+func (p *Pool) move(conn *Connection) error {
+	p.lock.Lock()
+
+	if p.closed {
+		p.lock.Unlock()
+		return nil
+	}
+
+	for i, c := range p.conns {
+		if c == conn {
+			p.conns = append(p.conns[:i], p.conns[i+1:]...)
+			break
+		}
+	}
+
+	var channels []string
+	handlers := make(map[string]Handler)
+
+	for ch, pc := range p.channels {
+		if pc.conn == conn {
+			channels = append(channels, ch)
+			handlers[ch] = pc.handler
+			delete(p.channels, ch)
+		}
+	}
+
+	p.lock.Unlock()
+
+	sort.Strings(channels)
+
+	for _, ch := range channels {
+		if err := p.JoinOne(ch, handlers[ch]); err != nil {
+			return errors.Wrap(err, "pool.move: could not move channels of a dead connection")
+		}
+	}
+
+	return nil
+}
+
+// Join joins the provided channels on the connections of the pool and attaches the provided handler to the channels.
+// If the handler is nil an empty twitchirc.ChannelHandler will be used.
+//
+// If the channel was already joined by the pool the handler will not be overwritten.
+func (p *Pool) Join(channels []string, handler Handler) error {
+	if handler == nil {
+		handler = &ChannelHandler{}
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.closed {
+		return errors.Wrap(ErrConnectionClosed, "pool.Join: pool was closed")
+	}
+
+	// The channels are only recorded as joined once all connections accepted them.
+	pending := make(map[string]*poolChannel)
+	assigned := make(map[*Connection][]string)
+	var order []*Connection
+
+	defer p.release(pending)
+
+	for _, ch := range channels {
+		ch = strings.ToLower(ch)
+
+		if _, ok := p.channels[ch]; ok {
+			continue
+		}
+
+		if _, ok := pending[ch]; ok {
+			continue
+		}
+
+		conn, err := p.connection()
+
+		if err != nil {
+			return errors.Wrapf(err, "pool.Join: could not join channel %s", ch)
+		}
+
+		// The lock was released if a new connection was opened, another Join could have taken the channel.
+		if _, ok := p.channels[ch]; ok {
+			continue
+		}
+
+		if _, ok := assigned[conn]; !ok {
+			order = append(order, conn)
+		}
+
+		assigned[conn] = append(assigned[conn], ch)
+		pending[ch] = &poolChannel{conn: conn, handler: handler}
+		p.reserved[conn]++
+	}
+
+	for i, conn := range order {
+		if err := conn.Join(assigned[conn], handler); err != nil {
+			p.rollback(order[:i], assigned)
+			return errors.Wrap(err, "pool.Join: could not join channels")
+		}
+	}
+
+	for ch, pc := range pending {
+		p.channels[ch] = pc
+	}
+
+	return nil
+}
+
+// release removes the reservations of the pending channels of a Join.
+// The caller must hold the lock of the pool.
+func (p *Pool) release(pending map[string]*poolChannel) {
+	for _, pc := range pending {
+		p.reserved[pc.conn]--
+
+		if p.reserved[pc.conn] <= 0 {
+			delete(p.reserved, pc.conn)
+		}
+	}
+}
+
+// rollback departs the channels which were assigned to the connections by a failed Join.
+func (p *Pool) rollback(conns []*Connection, assigned map[*Connection][]string) {
+	for _, conn := range conns {
+		for _, ch := range assigned[conn] {
+			conn.Depart(ch)
+		}
+	}
+}
+
+// JoinOne is the same as Join but with one channel only.
+func (p *Pool) JoinOne(channel string, handler Handler) error {
+	return p.Join([]string{channel}, handler)
+}
+
+// connection returns a connection which can hold another channel.
+// The channels reserved by running Joins count towards the channels of their connection.
+// If another Join dials a new connection already, connection waits for it instead of dialing too.
+// A new connection is opened if all connections are full.
+//
+// The caller must hold the lock of the pool. The lock is released while a new connection is dialed,
+// so the caller has to check the state of the pool again.
+func (p *Pool) connection() (*Connection, error) {
+	load := p.load()
+
+	for {
+		for _, conn := range p.conns {
+			if load[conn] < p.maxChannels {
+				return conn, nil
+			}
+		}
+
+		if !p.dialing {
+			break
+		}
+
+		// Another Join dials a new connection already, its connection can hold this channel too.
+		p.dialed.Wait()
+
+		if p.closed {
+			return nil, errors.Wrap(ErrConnectionClosed, "pool.connection: pool was closed")
+		}
+
+		load = p.load()
+	}
+
+	// Dialing can take a while, the other connections of the pool must not wait for it.
+	p.dialing = true
+	p.lock.Unlock()
+	conn, err := p.client.Connect(p.ircHandler)
+	p.lock.Lock()
+
+	p.dialing = false
+	p.dialed.Broadcast()
+
+	if err != nil {
+		return nil, errors.Wrap(err, "pool.connection: could not open a new connection")
+	}
+
+	if p.closed {
+		conn.Close()
+		return nil, errors.Wrap(ErrConnectionClosed, "pool.connection: pool was closed")
+	}
+
+	p.conns = append(p.conns, conn)
+
+	if p.ctx != nil {
+		p.start(conn)
+	}
+
+	return conn, nil
+}
+
+// load returns the number of channels of every connection including the reserved channels.
+// The caller must hold the lock of the pool.
+func (p *Pool) load() map[*Connection]int {
+	load := make(map[*Connection]int, len(p.conns))
+	for _, pc := range p.channels {
+		load[pc.conn]++
+	}
+
+	for conn, n := range p.reserved {
+		load[conn] += n
+	}
+
+	return load
+}
+
+// Depart leaves a channel and removes the handler.
+func (p *Pool) Depart(channel string) error {
+	channel = strings.ToLower(channel)
+
+	p.lock.Lock()
+	pc, ok := p.channels[channel]
+	delete(p.channels, channel)
+	p.lock.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	if err := pc.conn.Depart(channel); err != nil {
+		return errors.Wrapf(err, "pool.Depart: could not depart %s", channel)
+	}
+
+	return nil
+}
+
+// Say sends a PRIVMSG in the provided channel.
+//
+// The message is sent on the connection which joined the channel.
+// If the pool did not join the channel any connection of the pool is used.
+func (p *Pool) Say(channel, text string) error {
+	conn, err := p.channelConnection(channel)
+
+	if err != nil {
+		return errors.Wrap(err, "pool.Say: could not say message")
+	}
+
+	return conn.Say(channel, text)
+}
+
+// Send sends the message with its tags, like Say it uses the connection which joined the channel.
+func (p *Pool) Send(message *OutgoingMessage) error {
+	conn, err := p.channelConnection(message.Channel)
+
+	if err != nil {
+		return errors.Wrap(err, "pool.Send: could not send message")
+	}
+
+	return conn.Send(message)
+}
+
+// Reply sends text as a reply to parent in the channel of parent.
+func (p *Pool) Reply(parent *PrivateMessage, text string) error {
+	return p.Send(NewMessage(parent.Channel, text).ReplyTo(parent.ID))
+}
+
+// channelConnection returns the connection which joined channel or any connection
+// if the pool did not join the channel.
+func (p *Pool) channelConnection(channel string) (*Connection, error) {
+	channel = strings.ToLower(strings.TrimPrefix(channel, "#"))
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if pc, ok := p.channels[channel]; ok {
+		return pc.conn, nil
+	}
+
+	if len(p.conns) > 0 {
+		return p.conns[0], nil
+	}
+
+	return nil, errors.Wrapf(ErrConnectionClosed, "pool.channelConnection: no connection for %s", channel)
+}
+
+// Channels returns all channels joined by the pool.
+func (p *Pool) Channels() []string {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	channels := make([]string, 0, len(p.channels))
+	for ch := range p.channels {
+		channels = append(channels, ch)
+	}
+
+	sort.Strings(channels)
+
+	return channels
+}
+
+// Close closes all connections of the pool.
+//
+// A closed pool can't be used anymore, create a new pool if you want to reconnect.
+func (p *Pool) Close() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.closed {
+		return nil
+	}
+
+	p.closed = true
+	close(p.done)
+	p.dialed.Broadcast()
+
+	var firstErr error
+	for _, conn := range p.conns {
+		if err := conn.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
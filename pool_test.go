@@ -0,0 +1,236 @@
+package twitchirc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPool(t *testing.T) {
+	srv := newFakeServer()
+
+	client := NewAnonymousClient(&Config{})
+	client.dialer = srv.dial
+
+	pool := NewPool(client, nil, 2)
+
+	if err := pool.Join([]string{"a", "b", "c"}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	first := srv.accept(t)
+	srv.accept(t)
+
+	if got, want := pool.Channels(), []string{"a", "b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Channels() = %v, want %v", got, want)
+	}
+
+	pool.lock.Lock()
+	if len(pool.conns) != 2 {
+		t.Errorf("pool has %d connections, want 2", len(pool.conns))
+	}
+	dead := pool.channels["a"].conn
+	pool.lock.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+
+	go func() {
+		errCh <- pool.Run(ctx)
+	}()
+
+	// the channels of the dead connection get moved to a free or a new connection
+	first.Close()
+	srv.accept(t)
+
+	timeout := time.After(time.Second)
+
+	for {
+		pool.lock.Lock()
+		moved := pool.channels["a"] != nil && pool.channels["a"].conn != dead &&
+			pool.channels["b"] != nil && pool.channels["b"].conn != dead
+		pool.lock.Unlock()
+
+		if moved {
+			break
+		}
+
+		select {
+		case <-timeout:
+			t.Fatal("channels of the dead connection were not moved")
+		case <-time.After(time.Millisecond * 10):
+		}
+	}
+
+	if err := pool.Say("b", "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	srv.expect(t, "PRIVMSG #b :test")
+
+	if err := pool.Depart("c"); err != nil {
+		t.Fatal(err)
+	}
+
+	srv.expect(t, "PART #c")
+
+	if got, want := pool.Channels(), []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Channels() = %v, want %v", got, want)
+	}
+
+	cancel()
+
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPool_JoinFailure(t *testing.T) {
+	srv := newFakeServer()
+
+	client := NewAnonymousClient(&Config{})
+
+	dials := 0
+	client.dialer = func() (net.Conn, error) {
+		dials++
+		if dials > 1 {
+			return nil, errors.New("dial failed")
+		}
+
+		return srv.dial()
+	}
+
+	pool := NewPool(client, nil, 1)
+	defer pool.Close()
+
+	if err := pool.Join([]string{"a", "b"}, nil); err == nil {
+		t.Fatal("Join() error = nil, want dial error")
+	}
+
+	srv.accept(t)
+
+	if got := pool.Channels(); len(got) != 0 {
+		t.Errorf("Channels() = %v, want none", got)
+	}
+
+	// the channel was not joined, so joining it again must send the JOIN
+	if err := pool.JoinOne("a", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	srv.expect(t, "JOIN #a")
+
+	if got, want := pool.Channels(), []string{"a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Channels() = %v, want %v", got, want)
+	}
+}
+
+func TestPool_DialWithoutLock(t *testing.T) {
+	srv := newFakeServer()
+
+	client := NewAnonymousClient(&Config{})
+
+	dialing := make(chan struct{})
+	release := make(chan struct{})
+	client.dialer = func() (net.Conn, error) {
+		close(dialing)
+		<-release
+		return srv.dial()
+	}
+
+	pool := NewPool(client, nil, 1)
+	defer pool.Close()
+
+	joined := make(chan error, 1)
+	go func() {
+		joined <- pool.JoinOne("a", nil)
+	}()
+
+	<-dialing
+
+	channels := make(chan []string, 1)
+	go func() {
+		channels <- pool.Channels()
+	}()
+
+	select {
+	case got := <-channels:
+		if len(got) != 0 {
+			t.Errorf("Channels() = %v, want none", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Channels() was blocked by the dial")
+	}
+
+	close(release)
+
+	if err := <-joined; err != nil {
+		t.Fatal(err)
+	}
+
+	srv.accept(t)
+	srv.expect(t, "JOIN #a")
+}
+
+func TestPool_ConcurrentDial(t *testing.T) {
+	srv := newFakeServer()
+
+	client := NewAnonymousClient(&Config{})
+
+	var (
+		lock  sync.Mutex
+		dials int
+	)
+
+	dialing := make(chan struct{}, 10)
+	release := make(chan struct{})
+	client.dialer = func() (net.Conn, error) {
+		lock.Lock()
+		dials++
+		lock.Unlock()
+
+		dialing <- struct{}{}
+		<-release
+
+		return srv.dial()
+	}
+
+	pool := NewPool(client, nil, 2)
+	defer pool.Close()
+
+	joined := make(chan error, 2)
+	go func() {
+		joined <- pool.JoinOne("a", nil)
+	}()
+
+	<-dialing
+
+	go func() {
+		joined <- pool.JoinOne("b", nil)
+	}()
+
+	// the second Join finds the pool full and has to wait for the running dial
+	time.Sleep(time.Millisecond * 20)
+	close(release)
+
+	for i := 0; i < 2; i++ {
+		if err := <-joined; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if dials != 1 {
+		t.Errorf("pool dialed %d connections, want 1", dials)
+	}
+
+	if got, want := pool.Channels(), []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Channels() = %v, want %v", got, want)
+	}
+}
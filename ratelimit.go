@@ -0,0 +1,291 @@
+package twitchirc
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimit defines how many chat messages can be sent in a time window.
+//
+// Twitch counts the messages of all connections of an user together.
+// Messages in channels where the user is a moderator or the broadcaster
+// only count against ModMessages, all other messages count against both limits.
+type RateLimit struct {
+	// Messages is the limit for channels where the user is neither a moderator nor the broadcaster.
+	Messages int
+
+	// ModMessages is the limit for all messages.
+	ModMessages int
+
+	// Window is the time window of the limits.
+	Window time.Duration
+}
+
+var (
+	// DefaultRateLimit is the rate limit of a normal twitch account.
+	DefaultRateLimit = RateLimit{Messages: 20, ModMessages: 100, Window: time.Second * 30}
+
+	// KnownBotRateLimit is the rate limit of a known bot.
+	KnownBotRateLimit = RateLimit{Messages: 50, ModMessages: 100, Window: time.Second * 30}
+
+	// VerifiedBotRateLimit is the rate limit of a verified bot.
+	VerifiedBotRateLimit = RateLimit{Messages: 7500, ModMessages: 7500, Window: time.Second * 30}
+)
+
+// RateLimitMode decides what happens to a message which exceeds the rate limit.
+type RateLimitMode int
+
+const (
+	// RateLimitBlock blocks Say and Write until the message can be sent.
+	RateLimitBlock RateLimitMode = iota
+
+	// RateLimitQueue queues the message and returns immediately.
+	// Queued messages get sent in order once the limit allows it.
+	// If a queued message could not be sent, the next Say or Write of its connection returns a *QueueError.
+	RateLimitQueue
+
+	// RateLimitFail returns a *RateLimitError from Say and Write.
+	RateLimitFail
+)
+
+// RateLimitError is returned if a message exceeds the rate limit while RateLimitFail mode is used.
+type RateLimitError struct {
+	Channel string
+
+	// RetryAfter is the time until the message could be sent.
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("twitchirc: rate limit exceeded in channel %s, retry after %s", e.Channel, e.RetryAfter)
+}
+
+// QueueError is returned by Say and Write if an earlier queued message of the connection could not be sent
+// while RateLimitQueue mode is used. The new message is not queued then.
+type QueueError struct {
+	Channel string
+
+	// Message is the line which could not be sent.
+	Message string
+
+	Err error
+}
+
+func (e *QueueError) Error() string {
+	return fmt.Sprintf("twitchirc: queued message in channel %s could not be sent: %v", e.Channel, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *QueueError) Unwrap() error {
+	return e.Err
+}
+
+// privateMessageChannel returns the channel of message if it is a PRIVMSG.
+func privateMessageChannel(message string) (string, bool) {
+	msg, err := ParseMessage(message)
+
+	if err != nil || msg.Command != "PRIVMSG" || len(msg.Params) == 0 {
+		return "", false
+	}
+
+	return strings.TrimPrefix(msg.Params[0], "#"), true
+}
+
+// slidingWindow counts events in a sliding time window.
+type slidingWindow struct {
+	size   time.Duration
+	events []time.Time
+}
+
+// wait returns the time until n more events fit in the window without exceeding limit.
+func (w *slidingWindow) wait(now time.Time, limit, n int) time.Duration {
+	cutoff := now.Add(-w.size)
+
+	i := 0
+	for i < len(w.events) && !w.events[i].After(cutoff) {
+		i++
+	}
+	w.events = w.events[i:]
+
+	over := len(w.events) + n - limit
+	if over <= 0 {
+		return 0
+	}
+
+	if over > len(w.events) {
+		return w.size
+	}
+
+	return w.events[over-1].Sub(cutoff)
+}
+
+// add counts n events at now.
+func (w *slidingWindow) add(now time.Time, n int) {
+	for i := 0; i < n; i++ {
+		w.events = append(w.events, now)
+	}
+}
+
+// queuedMessage is a message waiting in the queue of the rate limiter.
+type queuedMessage struct {
+	conn      *Connection
+	channel   string
+	message   string
+	moderator bool
+}
+
+// rateLimiter limits the chat messages of all connections of a client.
+type rateLimiter struct {
+	limit RateLimit
+	mode  RateLimitMode
+
+	lock    sync.Mutex
+	normal  *slidingWindow
+	all     *slidingWindow
+	queue   []*queuedMessage
+	sending bool
+}
+
+func newRateLimiter(limit RateLimit, mode RateLimitMode) *rateLimiter {
+	if limit.Messages <= 0 {
+		limit.Messages = DefaultRateLimit.Messages
+	}
+
+	if limit.ModMessages <= 0 {
+		limit.ModMessages = DefaultRateLimit.ModMessages
+	}
+
+	if limit.Window <= 0 {
+		limit.Window = DefaultRateLimit.Window
+	}
+
+	return &rateLimiter{
+		limit:  limit,
+		mode:   mode,
+		normal: &slidingWindow{size: limit.Window},
+		all:    &slidingWindow{size: limit.Window},
+	}
+}
+
+// reserve counts a message and returns zero if it can be sent now.
+// Otherwise it returns the time until the message could be sent and does not count it.
+func (l *rateLimiter) reserve(moderator bool) time.Duration {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+
+	wait := l.all.wait(now, l.limit.ModMessages, 1)
+	if !moderator {
+		if w := l.normal.wait(now, l.limit.Messages, 1); w > wait {
+			wait = w
+		}
+	}
+
+	if wait > 0 {
+		return wait
+	}
+
+	l.all.add(now, 1)
+	if !moderator {
+		l.normal.add(now, 1)
+	}
+
+	return 0
+}
+
+// wait blocks until a message can be sent on conn or conn gets closed.
+func (l *rateLimiter) wait(conn *Connection, moderator bool) error {
+	return conn.waitReserved(func() time.Duration {
+		return l.reserve(moderator)
+	})
+}
+
+// send writes the message into conn according to the rate limit mode.
+func (l *rateLimiter) send(conn *Connection, channel, message string, moderator bool) error {
+	switch l.mode {
+	case RateLimitFail:
+		if wait := l.reserve(moderator); wait > 0 {
+			return &RateLimitError{Channel: channel, RetryAfter: wait}
+		}
+
+	case RateLimitQueue:
+		if queueErr := conn.takeQueueError(); queueErr != nil {
+			return queueErr
+		}
+
+		l.lock.Lock()
+		defer l.lock.Unlock()
+
+		l.queue = append(l.queue, &queuedMessage{conn: conn, channel: channel, message: message, moderator: moderator})
+
+		if !l.sending {
+			l.sending = true
+			go l.drain()
+		}
+
+		return nil
+
+	default:
+		if err := l.wait(conn, moderator); err != nil {
+			return err
+		}
+	}
+
+	_, err := conn.write(message)
+
+	return err
+}
+
+// drain sends the queued messages until the queue is empty.
+// Messages which could not be sent are reported to their connection.
+func (l *rateLimiter) drain() {
+	for {
+		l.lock.Lock()
+
+		if len(l.queue) == 0 {
+			l.sending = false
+			l.lock.Unlock()
+			return
+		}
+
+		next := l.queue[0]
+		l.queue = l.queue[1:]
+		l.lock.Unlock()
+
+		err := l.wait(next.conn, next.moderator)
+
+		if err == nil {
+			_, err = next.conn.write(next.message)
+		}
+
+		if err != nil {
+			next.conn.failQueued(&QueueError{Channel: next.channel, Message: next.message, Err: err})
+		}
+	}
+}
+
+// failQueued remembers the error of a queued message until the next Say or Write of the connection.
+// Only the first error is kept until it got returned.
+func (c *Connection) failQueued(queueErr *QueueError) {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+
+	if c.queueErr == nil {
+		c.queueErr = queueErr
+	}
+}
+
+// takeQueueError returns the error of a queued message and forgets it.
+// It returns nil if all queued messages were sent.
+func (c *Connection) takeQueueError() *QueueError {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+
+	queueErr := c.queueErr
+	c.queueErr = nil
+
+	return queueErr
+}
@@ -0,0 +1,187 @@
+package twitchirc
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func Test_slidingWindow_wait(t *testing.T) {
+	now := time.Now()
+
+	w := &slidingWindow{size: time.Second * 30}
+	w.add(now.Add(-time.Second*40), 1)
+	w.add(now.Add(-time.Second*20), 1)
+	w.add(now.Add(-time.Second*10), 1)
+
+	table := []struct {
+		name  string
+		limit int
+		want  time.Duration
+	}{
+		{"free", 3, 0},
+		{"oldest-expires", 2, time.Second * 10},
+		{"both-expire", 1, time.Second * 20},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := w.wait(now, tt.limit, 1); got != tt.want {
+				t.Errorf("wait() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_rateLimiter_reserve(t *testing.T) {
+	l := newRateLimiter(RateLimit{Messages: 2, ModMessages: 3, Window: time.Minute}, RateLimitFail)
+
+	if wait := l.reserve(false); wait != 0 {
+		t.Fatalf("reserve() = %v, want 0", wait)
+	}
+
+	if wait := l.reserve(false); wait != 0 {
+		t.Fatalf("reserve() = %v, want 0", wait)
+	}
+
+	if wait := l.reserve(false); wait == 0 {
+		t.Fatal("reserve() exceeded the limit for normal channels")
+	}
+
+	if wait := l.reserve(true); wait != 0 {
+		t.Fatalf("reserve() as moderator = %v, want 0", wait)
+	}
+
+	if wait := l.reserve(true); wait == 0 {
+		t.Fatal("reserve() as moderator exceeded the limit for all channels")
+	}
+}
+
+func TestConnection_Say_rateLimited(t *testing.T) {
+	srv := newFakeServer()
+
+	client := NewAnonymousClient(&Config{
+		CaptureTags:   true,
+		RateLimit:     &RateLimit{Messages: 1, ModMessages: 2, Window: time.Minute},
+		RateLimitMode: RateLimitFail,
+	})
+	client.dialer = srv.dial
+
+	conn, err := client.Connect(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	if err := conn.Say("julezdev", "first"); err != nil {
+		t.Fatal(err)
+	}
+
+	srv.expect(t, "PRIVMSG #julezdev :first")
+
+	var limitErr *RateLimitError
+	if err := conn.Say("julezdev", "second"); !errors.As(err, &limitErr) {
+		t.Fatalf("Say() error = %v, want *RateLimitError", err)
+	}
+
+	// moderators only count against the higher limit
+	if err := conn.handleLine("@badges=moderator/1;mod=1 :tmi.twitch.tv USERSTATE #julezdev"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := conn.Say("julezdev", "third"); err != nil {
+		t.Fatal(err)
+	}
+
+	srv.expect(t, "PRIVMSG #julezdev :third")
+
+	// other commands are not limited
+	if err := conn.Write("PART #julezdev"); err != nil {
+		t.Fatal(err)
+	}
+
+	srv.expect(t, "PART #julezdev")
+}
+
+func TestConnection_Say_queued(t *testing.T) {
+	srv := newFakeServer()
+
+	client := NewAnonymousClient(&Config{
+		RateLimit:     &RateLimit{Messages: 1, ModMessages: 1, Window: time.Millisecond * 50},
+		RateLimitMode: RateLimitQueue,
+	})
+	client.dialer = srv.dial
+
+	conn, err := client.Connect(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	for _, text := range []string{"one", "two", "three"} {
+		if err := conn.Say("julezdev", text); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	srv.expect(t, "PRIVMSG #julezdev :one")
+	srv.expect(t, "PRIVMSG #julezdev :two")
+	srv.expect(t, "PRIVMSG #julezdev :three")
+}
+
+func TestConnection_Say_queueError(t *testing.T) {
+	srv := newFakeServer()
+
+	client := NewAnonymousClient(&Config{
+		RateLimit:     &RateLimit{Messages: 1, ModMessages: 1, Window: time.Second},
+		RateLimitMode: RateLimitQueue,
+	})
+	client.dialer = srv.dial
+
+	conn, err := client.Connect(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, text := range []string{"one", "two"} {
+		if err := conn.Say("julezdev", text); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	srv.expect(t, "PRIVMSG #julezdev :one")
+
+	// the second message waits for the limit and can't be sent anymore
+	conn.Close()
+
+	timeout := time.After(time.Second)
+
+	for {
+		conn.connLock.Lock()
+		failed := conn.queueErr != nil
+		conn.connLock.Unlock()
+
+		if failed {
+			break
+		}
+
+		select {
+		case <-timeout:
+			t.Fatal("queued message was not reported")
+		case <-time.After(time.Millisecond * 10):
+		}
+	}
+
+	var queueErr *QueueError
+
+	err = conn.Say("julezdev", "three")
+	if !errors.As(err, &queueErr) {
+		t.Fatalf("Say() error = %v, want *QueueError", err)
+	}
+
+	if queueErr.Message != "PRIVMSG #julezdev :two" || !errors.Is(err, ErrConnectionClosed) {
+		t.Errorf("Say() error = %v, want the closed connection of the second message", err)
+	}
+}
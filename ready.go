@@ -0,0 +1,126 @@
+package twitchirc
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ErrAuthenticationFailed is returned if twitch rejected the nick or the oauth token.
+var ErrAuthenticationFailed = errors.New("twitchirc: authentication failed")
+
+// readiness tracks the login of a connection.
+// The zero value is a connection which did not log in yet.
+type readiness struct {
+	lock    sync.Mutex
+	ready   chan struct{}
+	welcome bool
+	err     error
+
+	// capReplies is the number of CAP ACK and CAP NAK replies to the CAP REQ messages.
+	capReplies   int
+	capabilities map[string]bool
+}
+
+// readyChan returns the channel which gets closed once the login succeeded or failed.
+// The caller must hold the lock.
+func (rd *readiness) readyChan() chan struct{} {
+	if rd.ready == nil {
+		rd.ready = make(chan struct{})
+	}
+
+	return rd.ready
+}
+
+// updateReadiness records the login messages of the connection.
+// It returns an error if twitch rejected a required capability.
+func (c *Connection) updateReadiness(msg *Message) error {
+	rd := &c.readiness
+
+	rd.lock.Lock()
+	defer rd.lock.Unlock()
+
+	switch msg.Command {
+	case "001":
+		rd.welcome = true
+	case "CAP":
+		if len(msg.Params) < 2 || (msg.Params[1] != "ACK" && msg.Params[1] != "NAK") {
+			return nil
+		}
+
+		rd.capReplies++
+
+		if err := c.updateCapabilities(msg); err != nil {
+			rd.err = err
+		}
+	case "NOTICE":
+		notice, err := ParseNotice(msg)
+
+		if err != nil || notice.Channel != "" {
+			return nil
+		}
+
+		if notice.ID == NoticeLoginAuthenticationFailed || notice.ID == NoticeImproperlyFormattedAuth {
+			rd.err = errors.Wrap(ErrAuthenticationFailed, notice.Text)
+		}
+	default:
+		return nil
+	}
+
+	ready := rd.readyChan()
+
+	select {
+	case <-ready:
+	default:
+		if rd.err != nil || (rd.welcome && rd.capReplies >= len(c.config.capabilityRequests())) {
+			close(ready)
+		}
+	}
+
+	if msg.Command == "CAP" && rd.err != nil {
+		return errors.Wrap(rd.err, "connection.updateReadiness: could not negotiate capabilities")
+	}
+
+	return nil
+}
+
+// loginError returns the error if twitch rejected the login or a required capability.
+func (c *Connection) loginError() error {
+	c.readiness.lock.Lock()
+	defer c.readiness.lock.Unlock()
+
+	return c.readiness.err
+}
+
+// WaitReady blocks until twitch confirmed the login of the connection.
+//
+// The login is confirmed by the 001 welcome message and, if capabilities were requested,
+// by the replies to all CAP REQ messages. WaitReady returns ErrAuthenticationFailed if twitch
+// rejected the nick or the oauth token, so an expired token can be detected right after Connect.
+// It returns ErrCapabilityRejected if a required capability was rejected.
+//
+// The messages of the login are read by Run, so Run must be running while WaitReady waits.
+func (c *Connection) WaitReady(ctx context.Context) error {
+	c.readiness.lock.Lock()
+	ready := c.readiness.readyChan()
+	c.readiness.lock.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "connection.WaitReady: login was not confirmed")
+	case <-c.closedSignal():
+		if err := c.loginError(); err != nil {
+			return errors.Wrap(err, "connection.WaitReady: could not log in")
+		}
+
+		return errors.Wrap(ErrConnectionClosed, "connection.WaitReady: connection was closed before the login was confirmed")
+	case <-ready:
+	}
+
+	if err := c.loginError(); err != nil {
+		return errors.Wrap(err, "connection.WaitReady: could not log in")
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package twitchirc
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestConnection_WaitReady(t *testing.T) {
+	t.Run("welcome", func(t *testing.T) {
+		server := newFakeServer()
+
+		client := NewClient("julezdev", "oauth:123", &Config{CaptureTags: true})
+		client.dialer = server.dial
+
+		conn, err := client.Connect(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		go conn.Run(ctx)
+
+		pipe := server.accept(t)
+		server.expect(t, "CAP REQ :twitch.tv/tags")
+
+		fmt.Fprint(pipe, ":tmi.twitch.tv 001 julezdev :Welcome, GLHF!\r\n")
+		fmt.Fprint(pipe, ":tmi.twitch.tv CAP * ACK :twitch.tv/tags\r\n")
+
+		if err := conn.WaitReady(ctx); err != nil {
+			t.Errorf("WaitReady() error = %v, want nil", err)
+		}
+	})
+
+	t.Run("authentication-failed", func(t *testing.T) {
+		server := newFakeServer()
+
+		client := NewClient("julezdev", "oauth:expired", &Config{Reconnect: &ReconnectConfig{}})
+		client.dialer = server.dial
+
+		conn, err := client.Connect(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		runErr := make(chan error, 1)
+		go func() {
+			runErr <- conn.Run(ctx)
+		}()
+
+		pipe := server.accept(t)
+		server.expect(t, "NICK julezdev")
+
+		fmt.Fprint(pipe, ":tmi.twitch.tv NOTICE * :Login authentication failed\r\n")
+
+		if err := conn.WaitReady(ctx); errors.Cause(err) != ErrAuthenticationFailed {
+			t.Errorf("WaitReady() error = %v, want %v", err, ErrAuthenticationFailed)
+		}
+
+		pipe.Close()
+
+		select {
+		case err := <-runErr:
+			if errors.Cause(err) != ErrAuthenticationFailed {
+				t.Errorf("Run() error = %v, want %v", err, ErrAuthenticationFailed)
+			}
+		case <-time.After(time.Second):
+			t.Error("Run() did not stop after the authentication failed")
+		}
+	})
+}
@@ -0,0 +1,330 @@
+package twitchirc
+
+import (
+	"bufio"
+	"context"
+	"hash/fnv"
+	"math/rand"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultMinBackoff = time.Second
+	defaultMaxBackoff = time.Minute * 2
+
+	// dedupeSize is the number of messages which are remembered during a RECONNECT.
+	dedupeSize = 10000
+)
+
+// handoverOverlap is the time both connections are read after a RECONNECT.
+var handoverOverlap = time.Second * 5
+
+// jitter is the random source of the reconnect backoff.
+// It is seeded once, so processes which lost the connection at the same time don't draw the same delays.
+var jitter = struct {
+	lock sync.Mutex
+	rand *rand.Rand
+}{rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+
+// ReconnectConfig configures how a Connection reconnects after the connection to the server was lost.
+type ReconnectConfig struct {
+	// MinBackoff is the delay before the first reconnect attempt.
+	// It doubles with every failed attempt. Defaults to one second.
+	MinBackoff time.Duration
+
+	// MaxBackoff is the upper limit of the delay between two attempts. Defaults to two minutes.
+	MaxBackoff time.Duration
+
+	// MaxAttempts is the number of attempts before Run gives up and returns an error.
+	// Zero means the connection retries forever.
+	MaxAttempts int
+}
+
+// backoff returns the delay before the provided attempt, starting at 1.
+//
+// The delay grows exponentially and gets a random jitter so many connections
+// which lost the connection at the same time don't reconnect at the same time.
+func (rc *ReconnectConfig) backoff(attempt int) time.Duration {
+	min, max := rc.MinBackoff, rc.MaxBackoff
+
+	if min <= 0 {
+		min = defaultMinBackoff
+	}
+
+	if max <= 0 {
+		max = defaultMaxBackoff
+	}
+
+	delay := min
+	for i := 1; i < attempt && delay < max; i++ {
+		delay *= 2
+	}
+
+	if delay > max {
+		delay = max
+	}
+
+	half := delay / 2
+
+	jitter.lock.Lock()
+	defer jitter.lock.Unlock()
+
+	return half + time.Duration(jitter.rand.Int63n(int64(delay-half)+1))
+}
+
+// reconnect dials the server until a new connection was created or all attempts failed.
+// The channels in the channel handler get scheduled to be joined again with their handlers.
+//
+// It returns the scanner of the new connection or nil if ctx was canceled or the connection was closed.
+func (c *Connection) reconnect(ctx context.Context) (*bufio.Scanner, error) {
+	conf := c.config.Reconnect
+
+	var lastErr error
+
+	for attempt := 1; conf.MaxAttempts == 0 || attempt <= conf.MaxAttempts; attempt++ {
+		delay := conf.backoff(attempt)
+
+		c.emitState(&StateChange{State: StateReconnecting, Attempt: attempt, Delay: delay, Err: lastErr})
+
+		done := c.closedSignal()
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, nil
+		case <-done:
+			timer.Stop()
+			return nil, nil
+		case <-timer.C:
+		}
+
+		conn, r, w, err := c.client.open()
+
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		if _, ok := c.replace(conn, r, w, false); !ok {
+			conn.Close()
+			return nil, nil
+		}
+
+		c.scheduleJoin(c.channels())
+
+		c.emitState(&StateChange{State: StateReconnected, Attempt: attempt})
+
+		return r, nil
+	}
+
+	if lastErr == nil {
+		return nil, errors.New("connection.reconnect: no reconnect attempts allowed")
+	}
+
+	return nil, errors.Wrapf(lastErr, "connection.reconnect: giving up after %d attempts", conf.MaxAttempts)
+}
+
+// handleReconnect replaces the connection after twitch sent a RECONNECT.
+//
+// The new connection joins all channels before it replaces the old connection.
+// Both connections get read for handoverOverlap so no message gets lost,
+// duplicates get dropped by their id or by their raw line if they have no id. If the new connection can't be created
+// the old connection is kept, once it gets closed by twitch the normal reconnect takes over.
+func (c *Connection) handleReconnect(ctx context.Context, results chan<- readResult) {
+	conn, r, w, err := c.client.open()
+
+	if err != nil {
+		return
+	}
+
+	if err = c.rejoin(w); err != nil {
+		conn.Close()
+		return
+	}
+
+	c.dedupe.start()
+
+	old, ok := c.replace(conn, r, w, true)
+
+	if !ok {
+		conn.Close()
+		return
+	}
+
+	go c.read(ctx, r, results)
+
+	done := c.closedSignal()
+	timer := time.NewTimer(handoverOverlap)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-done:
+	case <-timer.C:
+	}
+
+	old.Close()
+}
+
+// replace swaps the underlying connection and returns the old one.
+// It returns false if the connection was already closed.
+//
+// If keepPrevious is set the reader and writer of the old connection are kept,
+// so the PONG to a PING of the old connection can be sent while it is still read after a RECONNECT.
+func (c *Connection) replace(conn net.Conn, r *bufio.Scanner, w *bufio.Writer, keepPrevious bool) (net.Conn, bool) {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+
+	if c.closed {
+		return nil, false
+	}
+
+	if keepPrevious {
+		c.previousR, c.previousW = c.r, c.w
+	}
+
+	old := c.conn
+	c.conn, c.r, c.w = conn, r, w
+
+	return old, true
+}
+
+// rejoin joins every channel in the channel handler on the connection of w.
+// It respects the join limit and blocks until all channels were sent.
+func (c *Connection) rejoin(w *bufio.Writer) error {
+	limiter := c.joinLimiter()
+
+	channels := c.channels()
+
+	c.joins.lock.Lock()
+	c.joins.queued = nil
+	c.expectJoin(channels)
+	c.joins.lock.Unlock()
+
+	for len(channels) > 0 {
+		n := limiter.limit.Joins
+		if n > len(channels) {
+			n = len(channels)
+		}
+
+		batch := channels[:n]
+		channels = channels[n:]
+
+		err := c.waitReserved(func() time.Duration {
+			return limiter.reserve(len(batch))
+		})
+
+		if err != nil {
+			return errors.Wrap(err, "connection.rejoin: could not join channels")
+		}
+
+		for _, line := range joinLines(batch) {
+			if _, err := w.WriteString(line + "\r\n"); err != nil {
+				return errors.Wrapf(err, "connection.rejoin: could not join channels %s", batch)
+			}
+		}
+
+		if err := w.Flush(); err != nil {
+			return errors.Wrap(err, "connection.rejoin: could not flush buffer")
+		}
+	}
+
+	return nil
+}
+
+// emitState passes the state change to the irc handler if it implements StateHandler.
+func (c *Connection) emitState(change *StateChange) {
+	if h, ok := c.ircHandler.(StateHandler); ok {
+		h.HandleState(c, change)
+	}
+}
+
+// dedupe remembers the messages received while it is active to detect messages which were received on both connections.
+// The zero value is an inactive dedupe.
+type dedupe struct {
+	lock   sync.Mutex
+	active bool
+
+	// seen holds the connections which received a message that was not received on the other connection yet.
+	seen  map[string][]*bufio.Scanner
+	order []string
+}
+
+// start activates the dedupe.
+func (d *dedupe) start() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.active = true
+	d.seen = make(map[string][]*bufio.Scanner)
+	d.order = nil
+}
+
+// stop deactivates the dedupe and forgets all messages.
+func (d *dedupe) stop() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.active = false
+	d.seen = nil
+	d.order = nil
+}
+
+// dedupeKey returns the key of message, its id or a hash of the raw line if it has no id.
+func dedupeKey(message *Message) string {
+	if id, ok := message.GetTag("id"); ok && id != "" {
+		return "id:" + id
+	}
+
+	hash := fnv.New64a()
+	hash.Write([]byte(message.Message))
+
+	return "raw:" + strconv.FormatUint(hash.Sum64(), 16)
+}
+
+// duplicate reports whether message was already received on another connection than src
+// and remembers it otherwise.
+//
+// A message which is received twice on the same connection is not a duplicate, like two equal JOIN lines.
+// It always returns false if the dedupe is inactive.
+func (d *dedupe) duplicate(src *bufio.Scanner, message *Message) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if !d.active {
+		return false
+	}
+
+	key := dedupeKey(message)
+	sources := d.seen[key]
+
+	for i, s := range sources {
+		if s == src {
+			continue
+		}
+
+		if len(sources) == 1 {
+			delete(d.seen, key)
+		} else {
+			d.seen[key] = append(sources[:i:i], sources[i+1:]...)
+		}
+
+		return true
+	}
+
+	if len(d.order) >= dedupeSize {
+		delete(d.seen, d.order[0])
+		d.order = d.order[1:]
+	}
+
+	d.seen[key] = append(sources, src)
+	d.order = append(d.order, key)
+
+	return false
+}
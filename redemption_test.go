@@ -0,0 +1,42 @@
+package twitchirc
+
+import (
+	"testing"
+)
+
+func TestChannelHandler_rewardRedemption(t *testing.T) {
+	var redemptions []*PrivateMessage
+
+	handler := &ChannelHandler{
+		OnRewardRedemption: func(_ *Connection, m *PrivateMessage) { redemptions = append(redemptions, m) },
+	}
+
+	lines := []string{
+		"@custom-reward-id=4b6c3a4e-e8c3-4d1e-b1b3-5c6f5c0e2d3a;display-name=julezdev;id=1;user-id=530594933 :julezdev!julezdev@julezdev.tmi.twitch.tv PRIVMSG #lirik :read this out loud",
+		"@display-name=julezdev;id=2;msg-id=highlighted-message;user-id=530594933 :julezdev!julezdev@julezdev.tmi.twitch.tv PRIVMSG #lirik :look at me",
+		"@display-name=julezdev;id=3;msg-id=skip-subs-mode-message;user-id=530594933 :julezdev!julezdev@julezdev.tmi.twitch.tv PRIVMSG #lirik :let me in",
+		privEmote,
+	}
+
+	for _, line := range lines {
+		if err := handler.HandleIRC(nil, mustParseMessage(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(redemptions) != 3 {
+		t.Fatalf("OnRewardRedemption() got %d messages, want 3", len(redemptions))
+	}
+
+	if redemptions[0].CustomRewardID != "4b6c3a4e-e8c3-4d1e-b1b3-5c6f5c0e2d3a" {
+		t.Errorf("CustomRewardID = %q, want the reward id", redemptions[0].CustomRewardID)
+	}
+
+	if !redemptions[1].Highlighted || redemptions[1].SkipSubsMode {
+		t.Errorf("Highlighted = %v, SkipSubsMode = %v, want a highlighted message", redemptions[1].Highlighted, redemptions[1].SkipSubsMode)
+	}
+
+	if !redemptions[2].SkipSubsMode || redemptions[2].Highlighted {
+		t.Errorf("Highlighted = %v, SkipSubsMode = %v, want a message which skips the subs mode", redemptions[2].Highlighted, redemptions[2].SkipSubsMode)
+	}
+}
@@ -0,0 +1,144 @@
+package twitchirc
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RoomState holds the chat settings of a channel.
+type RoomState struct {
+	Channel string
+	RoomID  string
+
+	EmoteOnly bool
+	R9K       bool
+	SubsOnly  bool
+
+	// FollowersOnly is set if only followers can chat.
+	FollowersOnly bool
+
+	// FollowersOnlyDuration is the time a user must follow the channel before they can chat.
+	FollowersOnlyDuration time.Duration
+
+	// Slow is the time a user must wait between two messages, it is zero if slow mode is disabled.
+	Slow time.Duration
+}
+
+// RoomStateChange represents a parsed ROOMSTATE with the room state before and after the update.
+//
+// Twitch sends the complete room state after a channel was joined,
+// later ROOMSTATE messages only contain the changed settings.
+type RoomStateChange struct {
+	Channel string
+	Old     RoomState
+	New     RoomState
+
+	Raw *Message
+}
+
+// merge returns a copy of rs updated with all settings present in message.
+func (rs RoomState) merge(message *Message) RoomState {
+	if len(message.Params) > 0 {
+		rs.Channel = strings.TrimPrefix(message.Params[0], "#")
+	}
+
+	if roomID, ok := message.GetTag("room-id"); ok {
+		rs.RoomID = roomID
+	}
+
+	if emoteOnly, ok := message.GetTag("emote-only"); ok {
+		rs.EmoteOnly = emoteOnly == "1"
+	}
+
+	if r9k, ok := message.GetTag("r9k"); ok {
+		rs.R9K = r9k == "1"
+	}
+
+	if subsOnly, ok := message.GetTag("subs-only"); ok {
+		rs.SubsOnly = subsOnly == "1"
+	}
+
+	if followersOnly, ok := message.GetTag("followers-only"); ok {
+		minutes, err := strconv.Atoi(followersOnly)
+		rs.FollowersOnly = err == nil && minutes >= 0
+		rs.FollowersOnlyDuration = 0
+
+		if rs.FollowersOnly {
+			rs.FollowersOnlyDuration = time.Duration(minutes) * time.Minute
+		}
+	}
+
+	if slow, ok := message.GetTag("slow"); ok {
+		seconds, _ := strconv.Atoi(slow)
+		rs.Slow = time.Duration(seconds) * time.Second
+	}
+
+	return rs
+}
+
+// updateRoomState merges the ROOMSTATE message into the cached room state of channel.
+func (c *Connection) updateRoomState(channel string, message *Message) {
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+
+	if c.rooms == nil {
+		c.rooms = make(map[string]*RoomStateChange)
+	}
+
+	var old RoomState
+	if last, ok := c.rooms[channel]; ok {
+		old = last.New
+	}
+
+	c.rooms[channel] = &RoomStateChange{
+		Channel: channel,
+		Old:     old,
+		New:     old.merge(message),
+		Raw:     message,
+	}
+}
+
+// roomStateChange returns the change of the room state of channel caused by message.
+// It returns nil if message was not the last ROOMSTATE of the channel.
+func (c *Connection) roomStateChange(channel string, message *Message) *RoomStateChange {
+	if c == nil {
+		return nil
+	}
+
+	c.stateLock.RLock()
+	defer c.stateLock.RUnlock()
+
+	change, ok := c.rooms[channel]
+	if !ok || change.Raw != message {
+		return nil
+	}
+
+	return change
+}
+
+// forgetRoomState removes the cached room state of channel.
+func (c *Connection) forgetRoomState(channel string) {
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+
+	delete(c.rooms, channel)
+}
+
+// RoomState returns the current room state of a joined channel.
+//
+// The room state is only known after twitch sent a ROOMSTATE for the channel,
+// this requires Config.CaptureTags and Config.CaptureCommands.
+func (c *Connection) RoomState(channel string) (RoomState, bool) {
+	channel = strings.ToLower(channel)
+
+	c.stateLock.RLock()
+	defer c.stateLock.RUnlock()
+
+	change, ok := c.rooms[channel]
+	if !ok {
+		return RoomState{}, false
+	}
+
+	return change.New, true
+}
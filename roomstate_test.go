@@ -0,0 +1,79 @@
+package twitchirc
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	roomStateFull = "@emote-only=0;followers-only=10;r9k=0;rituals=0;room-id=23161357;slow=0;subs-only=0 :tmi.twitch.tv ROOMSTATE #lirik"
+	roomStateSlow = "@room-id=23161357;slow=30 :tmi.twitch.tv ROOMSTATE #lirik"
+)
+
+func TestRoomState_merge(t *testing.T) {
+	full := RoomState{}.merge(mustParseMessage(roomStateFull))
+
+	want := RoomState{
+		Channel:               "lirik",
+		RoomID:                "23161357",
+		FollowersOnly:         true,
+		FollowersOnlyDuration: time.Minute * 10,
+	}
+
+	if full != want {
+		t.Errorf("merge() = %+v, want %+v", full, want)
+	}
+
+	partial := full.merge(mustParseMessage(roomStateSlow))
+	want.Slow = time.Second * 30
+
+	if partial != want {
+		t.Errorf("merge() partial = %+v, want %+v", partial, want)
+	}
+
+	disabled := partial.merge(mustParseMessage("@followers-only=-1;room-id=23161357 :tmi.twitch.tv ROOMSTATE #lirik"))
+	want.FollowersOnly = false
+	want.FollowersOnlyDuration = 0
+
+	if disabled != want {
+		t.Errorf("merge() followers-only disabled = %+v, want %+v", disabled, want)
+	}
+}
+
+func TestConnection_RoomState(t *testing.T) {
+	var changes []*RoomStateChange
+
+	conn := &Connection{
+		config:      &Config{},
+		handlerLock: &sync.RWMutex{},
+		channelHandler: map[string]Handler{
+			"lirik": &ChannelHandler{
+				OnRoomStateChange: func(_ *Connection, c *RoomStateChange) { changes = append(changes, c) },
+			},
+		},
+	}
+
+	if _, ok := conn.RoomState("lirik"); ok {
+		t.Fatal("RoomState() returned a state before a ROOMSTATE was received")
+	}
+
+	for _, line := range []string{roomStateFull, roomStateSlow} {
+		if err := conn.handleLine(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	state, ok := conn.RoomState("Lirik")
+	if !ok || state.Slow != time.Second*30 || !state.FollowersOnly {
+		t.Errorf("RoomState() = %+v, want the merged room state", state)
+	}
+
+	if len(changes) != 2 {
+		t.Fatalf("OnRoomStateChange() called %d times, want 2", len(changes))
+	}
+
+	if changes[1].Old.Slow != 0 || changes[1].New.Slow != time.Second*30 || changes[1].Old.RoomID != "23161357" {
+		t.Errorf("OnRoomStateChange() = %+v, want the change of the slow mode", changes[1])
+	}
+}
@@ -0,0 +1,102 @@
+package twitchirc
+
+import (
+	"sync"
+)
+
+// defaultThreadMessages is the default number of messages a ThreadTracker remembers.
+const defaultThreadMessages = 1000
+
+// ThreadTracker remembers the latest messages of a channel grouped by their reply thread.
+//
+// Set it as ChannelHandler.Threads to track the messages of the channels of the handler.
+// Once the tracker holds its maximum number of messages the oldest message gets forgotten.
+type ThreadTracker struct {
+	maxMessages int
+
+	lock    sync.Mutex
+	order   []*PrivateMessage
+	threads map[string][]*PrivateMessage
+}
+
+// NewThreadTracker returns a ThreadTracker which remembers up to maxMessages messages.
+// If maxMessages is zero or less it remembers up to 1000 messages.
+func NewThreadTracker(maxMessages int) *ThreadTracker {
+	if maxMessages <= 0 {
+		maxMessages = defaultThreadMessages
+	}
+
+	return &ThreadTracker{
+		maxMessages: maxMessages,
+		threads:     make(map[string][]*PrivateMessage),
+	}
+}
+
+// threadID returns the id of the first message of the thread of message.
+func threadID(message *PrivateMessage) string {
+	if message.Reply != nil {
+		return message.Reply.ThreadID
+	}
+
+	return message.ID
+}
+
+// Add remembers message in its thread.
+// Every message which is not a reply starts a thread with its own id.
+func (t *ThreadTracker) Add(message *PrivateMessage) {
+	if message.ID == "" {
+		return
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	root := threadID(message)
+
+	t.threads[root] = append(t.threads[root], message)
+	t.order = append(t.order, message)
+
+	if len(t.order) <= t.maxMessages {
+		return
+	}
+
+	// The oldest message is also the oldest message of its thread.
+	oldest := t.order[0]
+	t.order[0] = nil
+	t.order = t.order[1:]
+
+	oldestRoot := threadID(oldest)
+
+	thread := t.threads[oldestRoot]
+	if len(thread) <= 1 {
+		delete(t.threads, oldestRoot)
+		return
+	}
+
+	t.threads[oldestRoot] = thread[1:]
+}
+
+// Thread returns the known messages of the thread with the root id in the order they were received.
+// The first message is the root if it is still remembered.
+func (t *ThreadTracker) Thread(rootID string) []*PrivateMessage {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	thread := t.threads[rootID]
+	if len(thread) == 0 {
+		return nil
+	}
+
+	messages := make([]*PrivateMessage, len(thread))
+	copy(messages, thread)
+
+	return messages
+}
+
+// Len returns the number of remembered messages.
+func (t *ThreadTracker) Len() int {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return len(t.order)
+}
@@ -0,0 +1,46 @@
+package twitchirc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestThreadTracker(t *testing.T) {
+	reply := func(id, parent, thread string) *PrivateMessage {
+		return &PrivateMessage{ID: id, Reply: &ReplyInfo{ParentID: parent, ThreadID: thread}}
+	}
+
+	root := &PrivateMessage{ID: "root"}
+	first := reply("first", "root", "root")
+	other := &PrivateMessage{ID: "other"}
+	second := reply("second", "first", "root")
+
+	tracker := NewThreadTracker(3)
+
+	handler := &ChannelHandler{Threads: tracker}
+	if err := handler.HandleIRC(nil, mustParseMessage(privEmote)); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, m := range []*PrivateMessage{root, first, other} {
+		tracker.Add(m)
+	}
+
+	if got := tracker.Thread("5bb550d4-bd15-4a96-9de2-c0298b2d01a9"); len(got) != 0 {
+		t.Errorf("Thread() = %v, want the message to be forgotten", got)
+	}
+
+	if want := []*PrivateMessage{root, first}; !reflect.DeepEqual(tracker.Thread("root"), want) {
+		t.Errorf("Thread() = %v, want %v", tracker.Thread("root"), want)
+	}
+
+	tracker.Add(second)
+
+	if want := []*PrivateMessage{first, second}; !reflect.DeepEqual(tracker.Thread("root"), want) {
+		t.Errorf("Thread() = %v, want %v", tracker.Thread("root"), want)
+	}
+
+	if tracker.Len() != 3 {
+		t.Errorf("Len() = %v, want 3", tracker.Len())
+	}
+}
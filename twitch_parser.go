@@ -13,6 +13,19 @@ type User struct {
 	Name        string
 	Color       string
 	Badges      map[string]int
+
+	// BadgeInfo holds the details of the badges, like the exact months of the subscriber badge.
+	BadgeInfo map[string]string
+
+	// Mod, Subscriber, VIP and Turbo are the legacy flags of the user.
+	// Use the methods of User which also check the badges.
+	Mod        bool
+	Subscriber bool
+	VIP        bool
+	Turbo      bool
+
+	// UserType is empty for normal users, otherwise mod, global_mod, admin or staff.
+	UserType string
 }
 
 // Emote represents a twitch emote.
@@ -20,10 +33,24 @@ type Emote struct {
 	Name  string
 	ID    string
 	Count int
+
+	// Positions are the positions of all occurrences of the emote in the text.
+	Positions []EmotePosition
+}
+
+// EmotePosition is the position of an emote in the text.
+//
+// Start and End are the positions of the first and the last rune of the emote,
+// the runes are counted in unicode code points like twitch does.
+type EmotePosition struct {
+	Start int
+	End   int
 }
 
 // parseEmotes creates a slice of emotes from emoteString.
 // It uses the message to slice the emote name out of the message.
+//
+// Malformed emotes and positions outside of the message are skipped.
 func parseEmotes(emoteString, message string) []*Emote {
 	emotes := []*Emote{}
 
@@ -35,28 +62,118 @@ func parseEmotes(emoteString, message string) []*Emote {
 
 	for _, v := range strings.Split(emoteString, "/") {
 		split := strings.SplitN(v, ":", 2)
-		pairs := strings.SplitN(split[1], ",", 2)
-		pair := strings.SplitN(pairs[0], "-", 2)
+		if len(split) != 2 || split[0] == "" {
+			continue
+		}
+
+		emote := &Emote{
+			ID: split[0],
+		}
 
-		firstIndex, _ := strconv.Atoi(pair[0])
-		lastIndex, _ := strconv.Atoi(pair[1])
+		for _, rawPosition := range strings.Split(split[1], ",") {
+			position, ok := parseEmotePosition(rawPosition, len(runes))
+			if !ok {
+				continue
+			}
 
-		if lastIndex+1 > len(runes) {
-			lastIndex--
+			if len(emote.Positions) == 0 {
+				emote.Name = string(runes[position.Start : position.End+1])
+			}
+
+			emote.Positions = append(emote.Positions, position)
 		}
 
-		emote := &Emote{
-			Name:  string(runes[firstIndex : lastIndex+1]),
-			ID:    split[0],
-			Count: strings.Count(split[1], ",") + 1,
+		if len(emote.Positions) == 0 {
+			continue
 		}
 
+		emote.Count = len(emote.Positions)
 		emotes = append(emotes, emote)
 	}
 
 	return emotes
 }
 
+// parseEmotePosition parses a start-end range of an emote in a message with length runes.
+// Ranges which are not inside of the message are rejected.
+func parseEmotePosition(rawPosition string, length int) (EmotePosition, bool) {
+	pair := strings.SplitN(rawPosition, "-", 2)
+	if len(pair) != 2 {
+		return EmotePosition{}, false
+	}
+
+	start, err := strconv.Atoi(pair[0])
+	if err != nil {
+		return EmotePosition{}, false
+	}
+
+	end, err := strconv.Atoi(pair[1])
+	if err != nil {
+		return EmotePosition{}, false
+	}
+
+	if start < 0 || start > end || end >= length {
+		return EmotePosition{}, false
+	}
+
+	return EmotePosition{Start: start, End: end}, true
+}
+
+// parseUser creates the user which sent message from the tags of message.
+//
+// The name is taken from the login tag and falls back to the prefix of the message.
+func parseUser(message *Message) *User {
+	user := &User{}
+
+	if badges, ok := message.GetTag("badges"); ok {
+		if badges != "" {
+			user.Badges = parseBadges(badges)
+		}
+	}
+
+	if badgeInfo, ok := message.GetTag("badge-info"); ok {
+		if badgeInfo != "" {
+			user.BadgeInfo = parseBadgeInfo(badgeInfo)
+		}
+	}
+
+	if displayName, ok := message.GetTag("display-name"); ok {
+		user.DisplayName = displayName
+	}
+
+	user.Mod = parseInt(message, "mod") == 1
+	user.Subscriber = parseInt(message, "subscriber") == 1
+	user.VIP = parseInt(message, "vip") == 1
+	user.Turbo = parseInt(message, "turbo") == 1
+
+	if userType, ok := message.GetTag("user-type"); ok {
+		user.UserType = userType
+	}
+
+	if userID, ok := message.GetTag("user-id"); ok {
+		user.ID = userID
+	}
+
+	if color, ok := message.GetTag("color"); ok {
+		user.Color = color
+	}
+
+	if login, ok := message.GetTag("login"); ok {
+		user.Name = login
+	} else if message.Prefix != nil {
+		user.Name = message.User
+	}
+
+	return user
+}
+
+// parseInt converts a numeric tag to an int, invalid values are 0.
+func parseInt(message *Message, key string) int {
+	value, _ := message.GetTag(key)
+	i, _ := strconv.Atoi(value)
+	return i
+}
+
 // parseTime converts the twitch tag time to golang's time.Time
 func parseTime(timeTag string) time.Time {
 	if timeTag == "" {
@@ -73,12 +190,31 @@ func parseBadges(badgesTag string) map[string]int {
 
 	for _, badge := range strings.Split(badgesTag, ",") {
 		pair := strings.SplitN(badge, "/", 2)
-		badges[pair[0]], _ = strconv.Atoi(pair[1])
+
+		if len(pair) == 2 && pair[0] != "" {
+			badges[pair[0]], _ = strconv.Atoi(pair[1])
+		}
 	}
 
 	return badges
 }
 
+// parseBadgeInfo parses the badge details from badgeInfoTag.
+// The values are kept as raw strings, like the months of founder or the outcome of predictions.
+func parseBadgeInfo(badgeInfoTag string) map[string]string {
+	badgeInfo := make(map[string]string)
+
+	for _, info := range strings.Split(badgeInfoTag, ",") {
+		pair := strings.SplitN(info, "/", 2)
+
+		if len(pair) == 2 {
+			badgeInfo[pair[0]] = pair[1]
+		}
+	}
+
+	return badgeInfo
+}
+
 // Ping related stuff
 
 // PingMessage represents a parsed PING.
@@ -104,33 +240,115 @@ type PrivateMessage struct {
 	Channel string
 	Time    time.Time
 
+	// Reply is set if the message is a reply to another message.
+	Reply *ReplyInfo
+
+	// Bits is the number of bits cheered with the message.
+	Bits int
+
+	// Cheermotes are the global cheermotes in the text, they are only parsed if the message has bits.
+	Cheermotes []*Cheermote
+
+	// CustomRewardID is the id of the custom channel points reward the message was sent with.
+	CustomRewardID string
+
+	// Highlighted is set if the message was highlighted with channel points.
+	Highlighted bool
+
+	// SkipSubsMode is set if the message was sent in subs only mode with channel points.
+	SkipSubsMode bool
+
 	Raw *Message
 }
 
-func parsePrivateMessage(message *Message) (*PrivateMessage, error) {
-	privateMessage := &PrivateMessage{
-		User: &User{},
-		Raw:  message,
+// IsRedemption reports whether the message was sent with channel points.
+func (m *PrivateMessage) IsRedemption() bool {
+	return m.CustomRewardID != "" || m.Highlighted || m.SkipSubsMode
+}
+
+// ReplyInfo describes the message a PRIVMSG replies to and the thread of the reply.
+type ReplyInfo struct {
+	// ParentID is the id of the message the reply answers.
+	ParentID   string
+	ParentUser *User
+	ParentText string
+
+	// ThreadID is the id of the first message of the thread.
+	// It is the ParentID if the parent is not a reply itself.
+	ThreadID   string
+	ThreadUser *User
+}
+
+// parseReply returns the ReplyInfo of message or nil if it is not a reply.
+func parseReply(message *Message) *ReplyInfo {
+	parentID, ok := message.GetTag("reply-parent-msg-id")
+	if !ok || parentID == "" {
+		return nil
 	}
 
-	if badges, ok := message.GetTag("badges"); ok {
-		if badges != "" {
-			privateMessage.User.Badges = parseBadges(badges)
-		}
+	reply := &ReplyInfo{
+		ParentID:   parentID,
+		ParentUser: &User{},
 	}
 
-	if emotes, ok := message.GetTag("emotes"); ok {
-		if emotes != "" {
-			privateMessage.Emotes = parseEmotes(emotes, message.Params[1])
-		}
+	if userID, ok := message.GetTag("reply-parent-user-id"); ok {
+		reply.ParentUser.ID = userID
 	}
 
-	if displayName, ok := message.GetTag("display-name"); ok {
-		privateMessage.User.DisplayName = displayName
+	if login, ok := message.GetTag("reply-parent-user-login"); ok {
+		reply.ParentUser.Name = login
 	}
 
-	if userID, ok := message.GetTag("user-id"); ok {
-		privateMessage.User.ID = userID
+	if displayName, ok := message.GetTag("reply-parent-display-name"); ok {
+		reply.ParentUser.DisplayName = displayName
+	}
+
+	if body, ok := message.GetTag("reply-parent-msg-body"); ok {
+		reply.ParentText = body
+	}
+
+	threadID, ok := message.GetTag("reply-thread-parent-msg-id")
+	if !ok || threadID == "" {
+		reply.ThreadID = reply.ParentID
+		reply.ThreadUser = reply.ParentUser
+		return reply
+	}
+
+	reply.ThreadID = threadID
+	reply.ThreadUser = &User{}
+
+	if userID, ok := message.GetTag("reply-thread-parent-user-id"); ok {
+		reply.ThreadUser.ID = userID
+	}
+
+	if login, ok := message.GetTag("reply-thread-parent-user-login"); ok {
+		reply.ThreadUser.Name = login
+	}
+
+	if displayName, ok := message.GetTag("reply-thread-parent-display-name"); ok {
+		reply.ThreadUser.DisplayName = displayName
+	}
+
+	return reply
+}
+
+// ParsePrivateMessage parses a PRIVMSG.
+//
+// It returns a *ParseError if the channel or the text is missing.
+func ParsePrivateMessage(message *Message) (*PrivateMessage, error) {
+	if len(message.Params) < 2 {
+		return nil, newParamError(message, 2)
+	}
+
+	privateMessage := &PrivateMessage{
+		User: parseUser(message),
+		Raw:  message,
+	}
+
+	if emotes, ok := message.GetTag("emotes"); ok {
+		if emotes != "" {
+			privateMessage.Emotes = parseEmotes(emotes, message.Params[1])
+		}
 	}
 
 	if messageID, ok := message.GetTag("id"); ok {
@@ -141,18 +359,27 @@ func parsePrivateMessage(message *Message) (*PrivateMessage, error) {
 		privateMessage.RoomID = roomID
 	}
 
-	if color, ok := message.GetTag("color"); ok {
-		privateMessage.User.Color = color
-	}
-
 	if time, ok := message.GetTag("tmi-sent-ts"); ok {
 		privateMessage.Time = parseTime(time)
 	}
 
-	privateMessage.User.Name = message.User
-
 	privateMessage.Channel = strings.TrimPrefix(message.Params[0], "#")
 	privateMessage.Text = message.Params[1]
+	privateMessage.Reply = parseReply(message)
+	privateMessage.Bits = parseInt(message, "bits")
+
+	if privateMessage.Bits > 0 {
+		privateMessage.Cheermotes = ParseCheermotes(privateMessage.Text, DefaultCheermotePrefixes)
+	}
+
+	if rewardID, ok := message.GetTag("custom-reward-id"); ok {
+		privateMessage.CustomRewardID = rewardID
+	}
+
+	if msgID, ok := message.GetTag("msg-id"); ok {
+		privateMessage.Highlighted = msgID == "highlighted-message"
+		privateMessage.SkipSubsMode = msgID == "skip-subs-mode-message"
+	}
 
 	return privateMessage, nil
 }
@@ -168,16 +395,17 @@ type WhisperMessage struct {
 	Raw *Message
 }
 
-func parseWhisper(message *Message) (*WhisperMessage, error) {
-	whisperMessage := &WhisperMessage{
-		User: &User{},
-		Raw:  message,
+// ParseWhisper parses a WHISPER.
+//
+// It returns a *ParseError if the text is missing.
+func ParseWhisper(message *Message) (*WhisperMessage, error) {
+	if len(message.Params) < 2 {
+		return nil, newParamError(message, 2)
 	}
 
-	if badges, ok := message.GetTag("badges"); ok {
-		if badges != "" {
-			whisperMessage.User.Badges = parseBadges(badges)
-		}
+	whisperMessage := &WhisperMessage{
+		User: parseUser(message),
+		Raw:  message,
 	}
 
 	if emotes, ok := message.GetTag("emotes"); ok {
@@ -186,18 +414,6 @@ func parseWhisper(message *Message) (*WhisperMessage, error) {
 		}
 	}
 
-	if displayName, ok := message.GetTag("display-name"); ok {
-		whisperMessage.User.DisplayName = displayName
-	}
-
-	if userID, ok := message.GetTag("user-id"); ok {
-		whisperMessage.User.ID = userID
-	}
-
-	if color, ok := message.GetTag("color"); ok {
-		whisperMessage.User.Color = color
-	}
-
 	if id, ok := message.GetTag("message-id"); ok {
 		whisperMessage.MessageID = id
 	}
@@ -207,7 +423,6 @@ func parseWhisper(message *Message) (*WhisperMessage, error) {
 	}
 
 	whisperMessage.Text = message.Params[1]
-	whisperMessage.User.Name = message.User
 
 	return whisperMessage, nil
 }
@@ -224,7 +439,8 @@ type ClearChatMessage struct {
 	Raw *Message
 }
 
-func parseClearChat(message *Message) (*ClearChatMessage, error) {
+// ParseClearChat parses a CLEARCHAT.
+func ParseClearChat(message *Message) (*ClearChatMessage, error) {
 	clearchatMessage := &ClearChatMessage{
 		Raw: message,
 	}
@@ -246,10 +462,58 @@ func parseClearChat(message *Message) (*ClearChatMessage, error) {
 		clearchatMessage.RoomID = roomID
 	}
 
-	if len(message.Params) > 1 {
+	if len(message.Params) > 0 {
 		clearchatMessage.Channel = strings.TrimLeft(message.Params[0], "#")
+	}
+
+	if len(message.Params) > 1 {
 		clearchatMessage.TargetUser = message.Params[1]
 	}
 
 	return clearchatMessage, nil
 }
+
+// ClearMessage represents a parsed clearmsg.
+//
+// It is sent when a moderator deleted a single message.
+type ClearMessage struct {
+	// TargetMessageID is the id of the deleted message.
+	TargetMessageID string
+
+	// Login is the name of the user who sent the deleted message.
+	Login   string
+	Channel string
+	Text    string
+	Time    time.Time
+
+	Raw *Message
+}
+
+// ParseClearMessage parses a CLEARMSG.
+func ParseClearMessage(message *Message) (*ClearMessage, error) {
+	clearMessage := &ClearMessage{
+		Raw: message,
+	}
+
+	if targetMessageID, ok := message.GetTag("target-msg-id"); ok {
+		clearMessage.TargetMessageID = targetMessageID
+	}
+
+	if login, ok := message.GetTag("login"); ok {
+		clearMessage.Login = login
+	}
+
+	if time, ok := message.GetTag("tmi-sent-ts"); ok {
+		clearMessage.Time = parseTime(time)
+	}
+
+	if len(message.Params) > 0 {
+		clearMessage.Channel = strings.TrimLeft(message.Params[0], "#")
+	}
+
+	if len(message.Params) > 1 {
+		clearMessage.Text = message.Params[1]
+	}
+
+	return clearMessage, nil
+}
@@ -12,6 +12,8 @@ const (
 	privEmote         = "@badge-info=;badges=broadcaster/1;client-nonce=ca3248e0c8cae6f2dcf913ceed1bc6be;color=#FFFFFF;display-name=julezdev;emotes=302213289:0-11,27-38/302242139:13-25;flags=;id=5bb550d4-bd15-4a96-9de2-c0298b2d01a9;mod=0;room-id=530594933;subscriber=0;tmi-sent-ts=1591719487292;turbo=0;user-id=530594933;user-type= :julezdev!julezdev@julezdev.tmi.twitch.tv PRIVMSG #julezdev :ratirlPickle ratirlPopcorn ratirlPickle test test"
 	timeout           = "@ban-duration=10;room-id=530594933;target-user-id=12427;tmi-sent-ts=1591726290782 :tmi.twitch.tv CLEARCHAT #julezdev :test"
 	ban               = "@room-id=530594933;target-user-id=19510;tmi-sent-ts=1591726324865 :tmi.twitch.tv CLEARCHAT #julezdev :bla"
+	privReply         = `@badge-info=;badges=;color=;display-name=shaymin_fakezz;emotes=;flags=;id=b34ccfc7-4977-403a-8a94-33c6bac34fb8;mod=0;reply-parent-display-name=julezdev;reply-parent-msg-body=ratirlPickle\stest;reply-parent-msg-id=5bb550d4-bd15-4a96-9de2-c0298b2d01a9;reply-parent-user-id=530594933;reply-parent-user-login=julezdev;reply-thread-parent-display-name=Lirik;reply-thread-parent-msg-id=885196de-cb67-427a-baa8-82f9b0fcd05f;reply-thread-parent-user-id=23161357;reply-thread-parent-user-login=lirik;room-id=530594933;subscriber=0;tmi-sent-ts=1591719487292;turbo=0;user-id=61083508;user-type= :shaymin_fakezz!shaymin_fakezz@shaymin_fakezz.tmi.twitch.tv PRIVMSG #julezdev :@julezdev hi`
+	clearMessage      = "@login=bla;room-id=;target-msg-id=5bb550d4-bd15-4a96-9de2-c0298b2d01a9;tmi-sent-ts=1591726324865 :tmi.twitch.tv CLEARMSG #julezdev :ratirlPickle test"
 )
 
 func Test_parseBadges(t *testing.T) {
@@ -61,7 +63,7 @@ func BenchmarkParsePrivateMessage(b *testing.B) {
 	b.ResetTimer()
 
 	for n := 0; n < b.N; n++ {
-		parsePrivateMessage(msg)
+		ParsePrivateMessage(msg)
 	}
 }
 
@@ -73,7 +75,7 @@ func BenchmarkParseWhisper(b *testing.B) {
 	b.ResetTimer()
 
 	for n := 0; n < b.N; n++ {
-		parseWhisper(msg)
+		ParseWhisper(msg)
 	}
 }
 
@@ -85,7 +87,7 @@ func BenchmarkParseClearchat(b *testing.B) {
 	b.ResetTimer()
 
 	for n := 0; n < b.N; n++ {
-		parseClearChat(msg)
+		ParseClearChat(msg)
 	}
 }
 
@@ -143,8 +145,8 @@ func Test_parseEmotes(t *testing.T) {
 				message:     "ratirlPickle ratirlPopcorn ratirlPickle test test",
 			},
 			want: []*Emote{
-				{ID: "302213289", Count: 2, Name: "ratirlPickle"},
-				{ID: "302242139", Count: 1, Name: "ratirlPopcorn"},
+				{ID: "302213289", Count: 2, Name: "ratirlPickle", Positions: []EmotePosition{{0, 11}, {27, 38}}},
+				{ID: "302242139", Count: 1, Name: "ratirlPopcorn", Positions: []EmotePosition{{13, 25}}},
 			},
 		},
 		{
@@ -154,7 +156,37 @@ func Test_parseEmotes(t *testing.T) {
 				message:     "bla xqcL",
 			},
 			want: []*Emote{
-				{ID: "1035663", Count: 1, Name: "xqcL"},
+				{ID: "1035663", Count: 1, Name: "xqcL", Positions: []EmotePosition{{4, 7}}},
+			},
+		},
+		{
+			name: "astral-plane",
+			args: args{
+				emoteString: "25:3-7",
+				message:     "😀😀 Kappa",
+			},
+			want: []*Emote{
+				{ID: "25", Count: 1, Name: "Kappa", Positions: []EmotePosition{{3, 7}}},
+			},
+		},
+		{
+			name: "malformed",
+			args: args{
+				emoteString: "25:0-4,x-9,7-3,40-45/bad/:1-2/1902:6-",
+				message:     "Kappa Keepo",
+			},
+			want: []*Emote{
+				{ID: "25", Count: 1, Name: "Kappa", Positions: []EmotePosition{{0, 4}}},
+			},
+		},
+		{
+			name: "outside-of-text",
+			args: args{
+				emoteString: "25:0-4/1902:6-11,0-9999",
+				message:     "Kappa Keepo",
+			},
+			want: []*Emote{
+				{ID: "25", Count: 1, Name: "Kappa", Positions: []EmotePosition{{0, 4}}},
 			},
 		},
 	}
@@ -191,7 +223,7 @@ func Test_parsePing(t *testing.T) {
 	}
 }
 
-func Test_parsePrivateMessage(t *testing.T) {
+func TestParsePrivateMessage(t *testing.T) {
 
 	type args struct {
 		message *Message
@@ -211,8 +243,8 @@ func Test_parsePrivateMessage(t *testing.T) {
 			want: &PrivateMessage{
 				Channel: "julezdev",
 				Emotes: []*Emote{
-					{ID: "302213289", Count: 2, Name: "ratirlPickle"},
-					{ID: "302242139", Count: 1, Name: "ratirlPopcorn"},
+					{ID: "302213289", Count: 2, Name: "ratirlPickle", Positions: []EmotePosition{{0, 11}, {27, 38}}},
+					{ID: "302242139", Count: 1, Name: "ratirlPopcorn", Positions: []EmotePosition{{13, 25}}},
 				},
 				ID:     "5bb550d4-bd15-4a96-9de2-c0298b2d01a9",
 				Raw:    &Message{},
@@ -228,26 +260,51 @@ func Test_parsePrivateMessage(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "reply",
+			args: args{
+				message: mustParseMessage(privReply),
+			},
+			want: &PrivateMessage{
+				Channel: "julezdev",
+				ID:      "b34ccfc7-4977-403a-8a94-33c6bac34fb8",
+				RoomID:  "530594933",
+				Text:    "@julezdev hi",
+				Time:    time.Unix(0, int64(1591719487292*1e6)),
+				User: &User{
+					DisplayName: "shaymin_fakezz",
+					ID:          "61083508",
+					Name:        "shaymin_fakezz",
+				},
+				Reply: &ReplyInfo{
+					ParentID:   "5bb550d4-bd15-4a96-9de2-c0298b2d01a9",
+					ParentUser: &User{ID: "530594933", Name: "julezdev", DisplayName: "julezdev"},
+					ParentText: "ratirlPickle test",
+					ThreadID:   "885196de-cb67-427a-baa8-82f9b0fcd05f",
+					ThreadUser: &User{ID: "23161357", Name: "lirik", DisplayName: "Lirik"},
+				},
+			},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.want.Raw = tt.args.message
 
-			got, err := parsePrivateMessage(tt.args.message)
+			got, err := ParsePrivateMessage(tt.args.message)
 
 			if (err != nil) != tt.wantErr {
-				t.Errorf("parsePrivateMessage() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("ParsePrivateMessage() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("parsePrivateMessage() = %v, want %v", got, tt.want)
+				t.Errorf("ParsePrivateMessage() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
-func Test_parseWhisper(t *testing.T) {
+func TestParseWhisper(t *testing.T) {
 	type args struct {
 		message *Message
 	}
@@ -273,9 +330,10 @@ func Test_parseWhisper(t *testing.T) {
 				},
 				Emotes: []*Emote{
 					{
-						ID:    "302213289",
-						Name:  "ratirlPickle",
-						Count: 1,
+						ID:        "302213289",
+						Name:      "ratirlPickle",
+						Count:     1,
+						Positions: []EmotePosition{{0, 11}},
 					},
 				},
 				Text: "ratirlPickle test",
@@ -298,9 +356,10 @@ func Test_parseWhisper(t *testing.T) {
 				},
 				Emotes: []*Emote{
 					{
-						ID:    "302213289",
-						Name:  "ratirlPickle",
-						Count: 1,
+						ID:        "302213289",
+						Name:      "ratirlPickle",
+						Count:     1,
+						Positions: []EmotePosition{{0, 11}},
 					},
 				},
 				Text: "ratirlPickle test",
@@ -311,20 +370,20 @@ func Test_parseWhisper(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.want.Raw = tt.args.message
 
-			got, err := parseWhisper(tt.args.message)
+			got, err := ParseWhisper(tt.args.message)
 
 			if (err != nil) != tt.wantErr {
-				t.Errorf("parseWhisper() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("ParseWhisper() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("parseWhisper() = %v, want %v", got, tt.want)
+				t.Errorf("ParseWhisper() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
-func Test_parseClearChat(t *testing.T) {
+func TestParseClearChat(t *testing.T) {
 	type args struct {
 		message *Message
 	}
@@ -368,14 +427,36 @@ func Test_parseClearChat(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.want.Raw = tt.args.message
 
-			got, err := parseClearChat(tt.args.message)
+			got, err := ParseClearChat(tt.args.message)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("parseClearChat() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("ParseClearChat() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("parseClearChat() = %v, want %v", got, tt.want)
+				t.Errorf("ParseClearChat() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+func TestParseClearMessage(t *testing.T) {
+	msg := mustParseMessage(clearMessage)
+
+	want := &ClearMessage{
+		TargetMessageID: "5bb550d4-bd15-4a96-9de2-c0298b2d01a9",
+		Login:           "bla",
+		Channel:         "julezdev",
+		Text:            "ratirlPickle test",
+		Time:            time.Unix(0, int64(1591726324865*1e6)),
+		Raw:             msg,
+	}
+
+	got, err := ParseClearMessage(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseClearMessage() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,112 @@
+package twitchirc
+
+import (
+	"strconv"
+)
+
+// PermissionLevel is the highest role of a user in a channel.
+// The levels are ordered, so they can be compared to check if a user has at least a role.
+type PermissionLevel int
+
+const (
+	// PermissionViewer is a user without a role.
+	PermissionViewer PermissionLevel = iota
+	// PermissionSubscriber is a subscriber of the channel.
+	PermissionSubscriber
+	// PermissionVIP is a vip of the channel.
+	PermissionVIP
+	// PermissionModerator is a moderator of the channel.
+	PermissionModerator
+	// PermissionBroadcaster is the owner of the channel.
+	PermissionBroadcaster
+	// PermissionStaff is a twitch staff member, admin or global moderator.
+	PermissionStaff
+)
+
+func (l PermissionLevel) String() string {
+	switch l {
+	case PermissionSubscriber:
+		return "subscriber"
+	case PermissionVIP:
+		return "vip"
+	case PermissionModerator:
+		return "moderator"
+	case PermissionBroadcaster:
+		return "broadcaster"
+	case PermissionStaff:
+		return "staff"
+	}
+
+	return "viewer"
+}
+
+// hasBadge reports whether the user has one of the badges.
+func (u *User) hasBadge(badges ...string) bool {
+	for _, badge := range badges {
+		if _, ok := u.Badges[badge]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsBroadcaster reports whether the user has the broadcaster badge.
+func (u *User) IsBroadcaster() bool {
+	return u.hasBadge("broadcaster")
+}
+
+// IsMod reports whether the user is a moderator of the channel.
+func (u *User) IsMod() bool {
+	return u.Mod || u.UserType == "mod" || u.hasBadge("moderator")
+}
+
+// IsVIP reports whether the user is a vip of the channel.
+func (u *User) IsVIP() bool {
+	return u.VIP || u.hasBadge("vip")
+}
+
+// IsSubscriber reports whether the user is a subscriber of the channel.
+func (u *User) IsSubscriber() bool {
+	return u.Subscriber || u.hasBadge("subscriber", "founder")
+}
+
+// IsStaff reports whether the user is a twitch staff member, admin or global moderator.
+func (u *User) IsStaff() bool {
+	switch u.UserType {
+	case "staff", "admin", "global_mod":
+		return true
+	}
+
+	return u.hasBadge("staff", "admin", "global_mod")
+}
+
+// SubMonths returns the number of months the user subscribed to the channel.
+// It is 0 if the user is not a subscriber or twitch did not send the badge-info tag.
+func (u *User) SubMonths() int {
+	for _, badge := range []string{"subscriber", "founder"} {
+		if months, err := strconv.Atoi(u.BadgeInfo[badge]); err == nil {
+			return months
+		}
+	}
+
+	return 0
+}
+
+// Permission returns the highest role of the user.
+func (u *User) Permission() PermissionLevel {
+	switch {
+	case u.IsStaff():
+		return PermissionStaff
+	case u.IsBroadcaster():
+		return PermissionBroadcaster
+	case u.IsMod():
+		return PermissionModerator
+	case u.IsVIP():
+		return PermissionVIP
+	case u.IsSubscriber():
+		return PermissionSubscriber
+	}
+
+	return PermissionViewer
+}
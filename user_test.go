@@ -0,0 +1,79 @@
+package twitchirc
+
+import (
+	"testing"
+)
+
+func TestUser_Permission(t *testing.T) {
+	tests := []struct {
+		name       string
+		line       string
+		want       PermissionLevel
+		subMonths  int
+		subscriber bool
+	}{
+		{
+			name: "viewer",
+			line: "@badge-info=;badges=;mod=0;subscriber=0;user-type= :bla!bla@bla.tmi.twitch.tv PRIVMSG #lirik :hi",
+			want: PermissionViewer,
+		},
+		{
+			name:       "founder",
+			line:       "@badge-info=founder/14;badges=founder/0;mod=0;subscriber=1;user-type= :bla!bla@bla.tmi.twitch.tv PRIVMSG #lirik :hi",
+			want:       PermissionSubscriber,
+			subMonths:  14,
+			subscriber: true,
+		},
+		{
+			name:       "vip",
+			line:       "@badge-info=subscriber/3;badges=vip/1,subscriber/3;mod=0;subscriber=1;user-type=;vip=1 :bla!bla@bla.tmi.twitch.tv PRIVMSG #lirik :hi",
+			want:       PermissionVIP,
+			subMonths:  3,
+			subscriber: true,
+		},
+		{
+			name: "legacy-mod",
+			line: "@badges=;mod=1;subscriber=0;user-type=mod :bla!bla@bla.tmi.twitch.tv PRIVMSG #lirik :hi",
+			want: PermissionModerator,
+		},
+		{
+			name:       "broadcaster",
+			line:       "@badge-info=subscriber/22;badges=broadcaster/1,subscriber/0;mod=0;subscriber=1;user-type= :lirik!lirik@lirik.tmi.twitch.tv PRIVMSG #lirik :hi",
+			want:       PermissionBroadcaster,
+			subMonths:  22,
+			subscriber: true,
+		},
+		{
+			name: "staff",
+			line: "@badges=staff/1;mod=0;subscriber=0;user-type=staff :bla!bla@bla.tmi.twitch.tv WHISPER julezdev :hi",
+			want: PermissionStaff,
+		},
+		{
+			name: "predicted",
+			line: `@badge-info=predictions/Team\sA;badges=predictions/blue-1;mod=0;subscriber=0;user-type= :bla!bla@bla.tmi.twitch.tv PRIVMSG #lirik :hi`,
+			want: PermissionViewer,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := parseUser(mustParseMessage(tt.line))
+
+			if got := user.Permission(); got != tt.want {
+				t.Errorf("Permission() = %v, want %v", got, tt.want)
+			}
+
+			if got := user.SubMonths(); got != tt.subMonths {
+				t.Errorf("SubMonths() = %v, want %v", got, tt.subMonths)
+			}
+
+			if got := user.IsSubscriber(); got != tt.subscriber {
+				t.Errorf("IsSubscriber() = %v, want %v", got, tt.subscriber)
+			}
+		})
+	}
+
+	if PermissionModerator < PermissionVIP || PermissionBroadcaster < PermissionModerator {
+		t.Error("permission levels are not ordered")
+	}
+}
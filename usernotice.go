@@ -0,0 +1,343 @@
+package twitchirc
+
+import (
+	"strings"
+	"time"
+)
+
+// SubPlan is the plan of a subscription.
+type SubPlan string
+
+const (
+	// SubPlanPrime is a subscription with twitch prime.
+	SubPlanPrime SubPlan = "Prime"
+	// SubPlanTier1 is a tier 1 subscription.
+	SubPlanTier1 SubPlan = "1000"
+	// SubPlanTier2 is a tier 2 subscription.
+	SubPlanTier2 SubPlan = "2000"
+	// SubPlanTier3 is a tier 3 subscription.
+	SubPlanTier3 SubPlan = "3000"
+)
+
+// Tier returns the tier of the plan from 1 to 3 or 0 if the plan is unknown.
+// Prime subscriptions are tier 1.
+func (p SubPlan) Tier() int {
+	switch p {
+	case SubPlanPrime, SubPlanTier1:
+		return 1
+	case SubPlanTier2:
+		return 2
+	case SubPlanTier3:
+		return 3
+	}
+
+	return 0
+}
+
+// UserNoticeMessage represents a parsed USERNOTICE.
+//
+// It is the base of all specialized USERNOTICE messages.
+type UserNoticeMessage struct {
+	ID      string
+	MsgID   string
+	RoomID  string
+	Channel string
+	User    *User
+	Emotes  []*Emote
+	Time    time.Time
+
+	// Text is the message the user attached, it is empty if the user did not attach a message.
+	Text string
+
+	// SystemMessage is the message twitch shows for the event.
+	SystemMessage string
+
+	Raw *Message
+}
+
+// ParseUserNotice parses a USERNOTICE into the base of all USERNOTICE messages.
+// Use ParseEvent to get the specialized message for the msg-id.
+func ParseUserNotice(message *Message) (*UserNoticeMessage, error) {
+	userNotice := &UserNoticeMessage{
+		User: parseUser(message),
+		Raw:  message,
+	}
+
+	if len(message.Params) > 1 {
+		userNotice.Text = message.Params[1]
+	}
+
+	if emotes, ok := message.GetTag("emotes"); ok {
+		if emotes != "" {
+			userNotice.Emotes = parseEmotes(emotes, userNotice.Text)
+		}
+	}
+
+	if id, ok := message.GetTag("id"); ok {
+		userNotice.ID = id
+	}
+
+	if msgID, ok := message.GetTag("msg-id"); ok {
+		userNotice.MsgID = msgID
+	}
+
+	if roomID, ok := message.GetTag("room-id"); ok {
+		userNotice.RoomID = roomID
+	}
+
+	if systemMessage, ok := message.GetTag("system-msg"); ok {
+		userNotice.SystemMessage = systemMessage
+	}
+
+	if time, ok := message.GetTag("tmi-sent-ts"); ok {
+		userNotice.Time = parseTime(time)
+	}
+
+	if len(message.Params) > 0 {
+		userNotice.Channel = strings.TrimPrefix(message.Params[0], "#")
+	}
+
+	return userNotice, nil
+}
+
+// SubscriptionMessage represents a parsed sub or resub USERNOTICE.
+type SubscriptionMessage struct {
+	*UserNoticeMessage
+
+	// CumulativeMonths is the total number of months the user subscribed.
+	CumulativeMonths int
+
+	// StreakMonths is the number of consecutive months, it is 0 if the user does not share the streak.
+	StreakMonths int
+	ShareStreak  bool
+
+	Plan     SubPlan
+	PlanName string
+}
+
+func parseSubscription(userNotice *UserNoticeMessage) *SubscriptionMessage {
+	message := userNotice.Raw
+
+	sub := &SubscriptionMessage{
+		UserNoticeMessage: userNotice,
+		CumulativeMonths:  parseInt(message, "msg-param-cumulative-months"),
+		StreakMonths:      parseInt(message, "msg-param-streak-months"),
+		ShareStreak:       parseInt(message, "msg-param-should-share-streak") == 1,
+	}
+
+	if plan, ok := message.GetTag("msg-param-sub-plan"); ok {
+		sub.Plan = SubPlan(plan)
+	}
+
+	if planName, ok := message.GetTag("msg-param-sub-plan-name"); ok {
+		sub.PlanName = planName
+	}
+
+	return sub
+}
+
+// GiftSubMessage represents a parsed subgift or anonsubgift USERNOTICE.
+type GiftSubMessage struct {
+	*UserNoticeMessage
+
+	// Anonymous is set if the gifter is anonymous.
+	Anonymous bool
+
+	// Recipient is the user who received the subscription.
+	Recipient *User
+
+	// Months is the total number of months the recipient subscribed.
+	Months int
+
+	// GiftMonths is the number of months which were gifted.
+	GiftMonths int
+
+	// SenderCount is the total number of subscriptions the gifter gifted in the channel.
+	SenderCount int
+
+	// OriginID is shared by all gifts of a mystery gift.
+	OriginID string
+
+	Plan     SubPlan
+	PlanName string
+}
+
+func parseGiftSub(userNotice *UserNoticeMessage) *GiftSubMessage {
+	message := userNotice.Raw
+
+	gift := &GiftSubMessage{
+		UserNoticeMessage: userNotice,
+		Anonymous:         isAnonymousGift(userNotice),
+		Recipient:         &User{},
+		Months:            parseInt(message, "msg-param-months"),
+		GiftMonths:        parseInt(message, "msg-param-gift-months"),
+		SenderCount:       parseInt(message, "msg-param-sender-count"),
+	}
+
+	if recipientID, ok := message.GetTag("msg-param-recipient-id"); ok {
+		gift.Recipient.ID = recipientID
+	}
+
+	if recipientName, ok := message.GetTag("msg-param-recipient-user-name"); ok {
+		gift.Recipient.Name = recipientName
+	}
+
+	if recipientDisplayName, ok := message.GetTag("msg-param-recipient-display-name"); ok {
+		gift.Recipient.DisplayName = recipientDisplayName
+	}
+
+	if originID, ok := message.GetTag("msg-param-origin-id"); ok {
+		gift.OriginID = originID
+	}
+
+	if plan, ok := message.GetTag("msg-param-sub-plan"); ok {
+		gift.Plan = SubPlan(plan)
+	}
+
+	if planName, ok := message.GetTag("msg-param-sub-plan-name"); ok {
+		gift.PlanName = planName
+	}
+
+	return gift
+}
+
+// MysteryGiftMessage represents a parsed submysterygift or anonsubmysterygift USERNOTICE.
+//
+// It is followed by one subgift USERNOTICE for every recipient with the same OriginID.
+type MysteryGiftMessage struct {
+	*UserNoticeMessage
+
+	// Anonymous is set if the gifter is anonymous.
+	Anonymous bool
+
+	// Count is the number of gifted subscriptions.
+	Count int
+
+	// SenderCount is the total number of subscriptions the gifter gifted in the channel.
+	SenderCount int
+
+	// OriginID is shared by all gifts of the mystery gift.
+	OriginID string
+
+	Plan SubPlan
+}
+
+func parseMysteryGift(userNotice *UserNoticeMessage) *MysteryGiftMessage {
+	message := userNotice.Raw
+
+	gift := &MysteryGiftMessage{
+		UserNoticeMessage: userNotice,
+		Anonymous:         isAnonymousGift(userNotice),
+		Count:             parseInt(message, "msg-param-mass-gift-count"),
+		SenderCount:       parseInt(message, "msg-param-sender-count"),
+	}
+
+	if originID, ok := message.GetTag("msg-param-origin-id"); ok {
+		gift.OriginID = originID
+	}
+
+	if plan, ok := message.GetTag("msg-param-sub-plan"); ok {
+		gift.Plan = SubPlan(plan)
+	}
+
+	return gift
+}
+
+// GiftUpgradeMessage represents a parsed giftpaidupgrade or anongiftpaidupgrade USERNOTICE.
+//
+// The user continues a gifted subscription with a paid subscription.
+type GiftUpgradeMessage struct {
+	*UserNoticeMessage
+
+	// Anonymous is set if the original gifter is anonymous.
+	Anonymous bool
+
+	// Sender is the user who gifted the original subscription, it is nil if the gifter is anonymous.
+	Sender *User
+
+	PromoName      string
+	PromoGiftTotal int
+}
+
+func parseGiftUpgrade(userNotice *UserNoticeMessage) *GiftUpgradeMessage {
+	message := userNotice.Raw
+
+	upgrade := &GiftUpgradeMessage{
+		UserNoticeMessage: userNotice,
+		Anonymous:         isAnonymousGift(userNotice),
+		PromoGiftTotal:    parseInt(message, "msg-param-promo-gift-total"),
+	}
+
+	if !upgrade.Anonymous {
+		upgrade.Sender = &User{}
+
+		if senderName, ok := message.GetTag("msg-param-sender-login"); ok {
+			upgrade.Sender.Name = senderName
+		}
+
+		if senderDisplayName, ok := message.GetTag("msg-param-sender-name"); ok {
+			upgrade.Sender.DisplayName = senderDisplayName
+		}
+	}
+
+	if promoName, ok := message.GetTag("msg-param-promo-name"); ok {
+		upgrade.PromoName = promoName
+	}
+
+	return upgrade
+}
+
+// isAnonymousGift reports whether the gift was sent by an anonymous gifter.
+func isAnonymousGift(userNotice *UserNoticeMessage) bool {
+	return strings.HasPrefix(userNotice.MsgID, "anon") || userNotice.User.Name == "ananonymousgifter"
+}
+
+// RaidEvent represents a parsed raid USERNOTICE.
+type RaidEvent struct {
+	*UserNoticeMessage
+
+	// Raider is the broadcaster who raided the channel.
+	Raider *User
+
+	// ViewerCount is the number of viewers who joined the raid.
+	ViewerCount int
+
+	ProfileImageURL string
+}
+
+func parseRaid(userNotice *UserNoticeMessage) *RaidEvent {
+	message := userNotice.Raw
+
+	raid := &RaidEvent{
+		UserNoticeMessage: userNotice,
+		Raider:            &User{ID: userNotice.User.ID},
+		ViewerCount:       parseInt(message, "msg-param-viewerCount"),
+	}
+
+	if login, ok := message.GetTag("msg-param-login"); ok {
+		raid.Raider.Name = login
+	}
+
+	if displayName, ok := message.GetTag("msg-param-displayName"); ok {
+		raid.Raider.DisplayName = displayName
+	}
+
+	if profileImageURL, ok := message.GetTag("msg-param-profileImageURL"); ok {
+		raid.ProfileImageURL = profileImageURL
+	}
+
+	return raid
+}
+
+// UnraidEvent represents a parsed unraid USERNOTICE.
+//
+// It is sent in the channel which canceled its raid.
+type UnraidEvent struct {
+	*UserNoticeMessage
+}
+
+func parseUnraid(userNotice *UserNoticeMessage) *UnraidEvent {
+	return &UnraidEvent{
+		UserNoticeMessage: userNotice,
+	}
+}
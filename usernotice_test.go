@@ -0,0 +1,166 @@
+package twitchirc
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+const (
+	resub       = `@badge-info=subscriber/8;badges=subscriber/6,premium/1;color=#1E90FF;display-name=julezdev;emotes=;flags=;id=b9d5f1c8-1f36-4a6a-8d24-7a1b1e6d5c31;login=julezdev;mod=0;msg-id=resub;msg-param-cumulative-months=8;msg-param-months=0;msg-param-should-share-streak=1;msg-param-streak-months=3;msg-param-sub-plan-name=Channel\sSubscription\s(lirik);msg-param-sub-plan=Prime;room-id=23161357;subscriber=1;system-msg=julezdev\ssubscribed\swith\sTwitch\sPrime.;tmi-sent-ts=1591719487292;user-id=530594933;user-type= :tmi.twitch.tv USERNOTICE #lirik :great stream`
+	subGift     = `@badge-info=;badges=;color=;display-name=shaymin_fakezz;emotes=;flags=;id=e9176cd8-5e22-4684-ad40-ce53c2561c5e;login=shaymin_fakezz;mod=0;msg-id=subgift;msg-param-gift-months=1;msg-param-months=2;msg-param-origin-id=da\s39\sa3\see\s5e\s6b\s4b\s0d;msg-param-recipient-display-name=julezdev;msg-param-recipient-id=530594933;msg-param-recipient-user-name=julezdev;msg-param-sender-count=5;msg-param-sub-plan-name=Channel\sSubscription;msg-param-sub-plan=1000;room-id=23161357;subscriber=0;system-msg=shaymin_fakezz\sgifted\sa\sTier\s1\ssub\sto\sjulezdev!;tmi-sent-ts=1591719487292;user-id=61083508;user-type= :tmi.twitch.tv USERNOTICE #lirik`
+	mysteryGift = `@badge-info=;badges=;color=;display-name=AnAnonymousGifter;emotes=;flags=;id=1a2b3c;login=ananonymousgifter;mod=0;msg-id=submysterygift;msg-param-mass-gift-count=50;msg-param-origin-id=da\s39\sa3\see\s5e\s6b\s4b\s0d;msg-param-sub-plan=2000;room-id=23161357;subscriber=0;system-msg=An\sanonymous\suser\sis\sgifting\s50\sTier\s2\sSubs!;tmi-sent-ts=1591719487292;user-id=274598607;user-type= :tmi.twitch.tv USERNOTICE #lirik`
+	raid        = `@badge-info=;badges=partner/1;color=#5B99FF;display-name=Lirik;emotes=;flags=;id=3d830f12-795c-447d-af3c-ea05e40fbddb;login=lirik;mod=0;msg-id=raid;msg-param-displayName=Lirik;msg-param-login=lirik;msg-param-profileImageURL=https://static-cdn.jtvnw.net/jtv_user_pictures/lirik-profile_image-70x70.png;msg-param-viewerCount=1337;room-id=530594933;subscriber=0;system-msg=1337\sraiders\sfrom\sLirik\shave\sjoined!;tmi-sent-ts=1591719487292;user-id=23161357;user-type= :tmi.twitch.tv USERNOTICE #julezdev`
+	giftUpgrade = `@badge-info=;badges=;color=;display-name=julezdev;emotes=;flags=;id=4c5d;login=julezdev;mod=0;msg-id=giftpaidupgrade;msg-param-sender-login=shaymin_fakezz;msg-param-sender-name=Shaymin_Fakezz;room-id=23161357;subscriber=1;system-msg=julezdev\sis\scontinuing\sthe\sGift\sSub;tmi-sent-ts=1591719487292;user-id=530594933;user-type= :tmi.twitch.tv USERNOTICE #lirik`
+)
+
+func TestParseUserNotice(t *testing.T) {
+	msg := mustParseMessage(resub)
+
+	want := &UserNoticeMessage{
+		ID:      "b9d5f1c8-1f36-4a6a-8d24-7a1b1e6d5c31",
+		MsgID:   "resub",
+		RoomID:  "23161357",
+		Channel: "lirik",
+		User: &User{
+			ID:          "530594933",
+			Name:        "julezdev",
+			DisplayName: "julezdev",
+			Color:       "#1E90FF",
+			Badges:      map[string]int{"subscriber": 6, "premium": 1},
+			BadgeInfo:   map[string]string{"subscriber": "8"},
+			Subscriber:  true,
+		},
+		Time:          time.Unix(0, int64(1591719487292*1e6)),
+		Text:          "great stream",
+		SystemMessage: "julezdev subscribed with Twitch Prime.",
+		Raw:           msg,
+	}
+
+	got, err := ParseUserNotice(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseUserNotice() = %v, want %v", got, want)
+	}
+}
+
+func Test_parseSubscription(t *testing.T) {
+	userNotice, _ := ParseUserNotice(mustParseMessage(resub))
+
+	want := &SubscriptionMessage{
+		UserNoticeMessage: userNotice,
+		CumulativeMonths:  8,
+		StreakMonths:      3,
+		ShareStreak:       true,
+		Plan:              SubPlanPrime,
+		PlanName:          "Channel Subscription (lirik)",
+	}
+
+	if got := parseSubscription(userNotice); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseSubscription() = %v, want %v", got, want)
+	}
+}
+
+func Test_parseGiftSub(t *testing.T) {
+	userNotice, _ := ParseUserNotice(mustParseMessage(subGift))
+
+	want := &GiftSubMessage{
+		UserNoticeMessage: userNotice,
+		Recipient:         &User{ID: "530594933", Name: "julezdev", DisplayName: "julezdev"},
+		Months:            2,
+		GiftMonths:        1,
+		SenderCount:       5,
+		OriginID:          "da 39 a3 ee 5e 6b 4b 0d",
+		Plan:              SubPlanTier1,
+		PlanName:          "Channel Subscription",
+	}
+
+	if got := parseGiftSub(userNotice); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseGiftSub() = %v, want %v", got, want)
+	}
+}
+
+func Test_parseMysteryGift(t *testing.T) {
+	userNotice, _ := ParseUserNotice(mustParseMessage(mysteryGift))
+
+	want := &MysteryGiftMessage{
+		UserNoticeMessage: userNotice,
+		Anonymous:         true,
+		Count:             50,
+		OriginID:          "da 39 a3 ee 5e 6b 4b 0d",
+		Plan:              SubPlanTier2,
+	}
+
+	if got := parseMysteryGift(userNotice); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseMysteryGift() = %v, want %v", got, want)
+	}
+}
+
+func Test_parseGiftUpgrade(t *testing.T) {
+	userNotice, _ := ParseUserNotice(mustParseMessage(giftUpgrade))
+
+	want := &GiftUpgradeMessage{
+		UserNoticeMessage: userNotice,
+		Sender:            &User{Name: "shaymin_fakezz", DisplayName: "Shaymin_Fakezz"},
+	}
+
+	if got := parseGiftUpgrade(userNotice); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseGiftUpgrade() = %v, want %v", got, want)
+	}
+}
+
+func Test_parseRaid(t *testing.T) {
+	userNotice, _ := ParseUserNotice(mustParseMessage(raid))
+
+	want := &RaidEvent{
+		UserNoticeMessage: userNotice,
+		Raider:            &User{ID: "23161357", Name: "lirik", DisplayName: "Lirik"},
+		ViewerCount:       1337,
+		ProfileImageURL:   "https://static-cdn.jtvnw.net/jtv_user_pictures/lirik-profile_image-70x70.png",
+	}
+
+	if got := parseRaid(userNotice); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseRaid() = %v, want %v", got, want)
+	}
+}
+
+func TestChannelHandler_HandleIRC_userNotice(t *testing.T) {
+	var (
+		subs  []*SubscriptionMessage
+		gifts []*GiftSubMessage
+		raids []*RaidEvent
+		all   int
+	)
+
+	handler := &ChannelHandler{
+		OnUserNotice:   func(_ *Connection, m *UserNoticeMessage) { all++ },
+		OnSubscription: func(_ *Connection, m *SubscriptionMessage) { subs = append(subs, m) },
+		OnGiftSub:      func(_ *Connection, m *GiftSubMessage) { gifts = append(gifts, m) },
+		OnRaid:         func(_ *Connection, m *RaidEvent) { raids = append(raids, m) },
+	}
+
+	for _, line := range []string{resub, subGift, mysteryGift, raid} {
+		if err := handler.HandleIRC(nil, mustParseMessage(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if all != 4 {
+		t.Errorf("OnUserNotice() called %d times, want 4", all)
+	}
+
+	if len(subs) != 1 || subs[0].CumulativeMonths != 8 {
+		t.Errorf("OnSubscription() = %v, want the resub", subs)
+	}
+
+	if len(gifts) != 1 || gifts[0].Recipient.Name != "julezdev" {
+		t.Errorf("OnGiftSub() = %v, want the subgift", gifts)
+	}
+
+	if len(raids) != 1 || raids[0].ViewerCount != 1337 {
+		t.Errorf("OnRaid() = %v, want the raid", raids)
+	}
+}
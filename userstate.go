@@ -0,0 +1,139 @@
+package twitchirc
+
+import (
+	"strings"
+)
+
+// UserState represents a parsed USERSTATE or GLOBALUSERSTATE.
+//
+// It describes the connected user, either globally or in a specific channel.
+type UserState struct {
+	// Channel is the channel of a USERSTATE, it is empty for a GLOBALUSERSTATE.
+	Channel string
+	User    *User
+
+	// EmoteSets are the ids of the emote sets the user can use.
+	EmoteSets []string
+
+	Raw *Message
+}
+
+// ParseUserState parses a USERSTATE or GLOBALUSERSTATE.
+func ParseUserState(message *Message) (*UserState, error) {
+	userState := &UserState{
+		User: parseUser(message),
+		Raw:  message,
+	}
+
+	// The prefix of a USERSTATE is the server and not the user.
+	userState.User.Name = ""
+
+	if emoteSets, ok := message.GetTag("emote-sets"); ok {
+		if emoteSets != "" {
+			userState.EmoteSets = strings.Split(emoteSets, ",")
+		}
+	}
+
+	if message.Command == "USERSTATE" && len(message.Params) > 0 {
+		userState.Channel = strings.TrimPrefix(message.Params[0], "#")
+	}
+
+	return userState, nil
+}
+
+// updateUserState parses a USERSTATE or GLOBALUSERSTATE and stores it.
+//
+// The name of the user is taken from the client and the id of a USERSTATE
+// from the last GLOBALUSERSTATE, because twitch does not send them.
+func (c *Connection) updateUserState(message *Message) {
+	userState, err := ParseUserState(message)
+
+	if err != nil {
+		return
+	}
+
+	if c.client != nil {
+		userState.User.Name = c.client.nick
+	}
+
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+
+	if message.Command == "GLOBALUSERSTATE" {
+		c.self = userState
+		return
+	}
+
+	if userState.User.ID == "" && c.self != nil {
+		userState.User.ID = c.self.User.ID
+	}
+
+	if c.userStates == nil {
+		c.userStates = make(map[string]*UserState)
+	}
+
+	c.userStates[userState.Channel] = userState
+}
+
+// forgetUserState removes the cached user state of channel.
+func (c *Connection) forgetUserState(channel string) {
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+
+	delete(c.userStates, channel)
+}
+
+// cachedUserState returns the stored user state for message.
+// It falls back to parsing message if the connection did not store it.
+func (c *Connection) cachedUserState(message *Message) (*UserState, error) {
+	if c != nil {
+		c.stateLock.RLock()
+		defer c.stateLock.RUnlock()
+
+		if c.self != nil && c.self.Raw == message {
+			return c.self, nil
+		}
+
+		if len(message.Params) > 0 {
+			userState, ok := c.userStates[strings.TrimPrefix(message.Params[0], "#")]
+			if ok && userState.Raw == message {
+				return userState, nil
+			}
+		}
+	}
+
+	return ParseUserState(message)
+}
+
+// Self returns the connected user from the last GLOBALUSERSTATE.
+//
+// Twitch sends a GLOBALUSERSTATE after the login if Config.CaptureTags and Config.CaptureCommands
+// are set. Anonymous users don't receive one, so Self returns nil for them.
+func (c *Connection) Self() *UserState {
+	c.stateLock.RLock()
+	defer c.stateLock.RUnlock()
+
+	return c.self
+}
+
+// UserState returns the connected user in a joined channel from the last USERSTATE of the channel.
+func (c *Connection) UserState(channel string) (*UserState, bool) {
+	channel = strings.ToLower(channel)
+
+	c.stateLock.RLock()
+	defer c.stateLock.RUnlock()
+
+	userState, ok := c.userStates[channel]
+
+	return userState, ok
+}
+
+// isModerator reports whether the user is a moderator or the broadcaster in channel.
+func (c *Connection) isModerator(channel string) bool {
+	userState, ok := c.UserState(channel)
+	if !ok {
+		return false
+	}
+
+	return userState.User.IsMod() || userState.User.IsBroadcaster()
+}
@@ -0,0 +1,91 @@
+package twitchirc
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+const (
+	globalUserState = "@badge-info=;badges=;color=#FFFFFF;display-name=julezdev;emote-sets=0,33563,231890,300206296;user-id=530594933;user-type= :tmi.twitch.tv GLOBALUSERSTATE"
+	userStateMod    = "@badge-info=;badges=moderator/1;color=#FFFFFF;display-name=julezdev;emote-sets=0,33563;mod=1;subscriber=0;user-type=mod :tmi.twitch.tv USERSTATE #lirik"
+)
+
+func TestParseUserState(t *testing.T) {
+	msg := mustParseMessage(userStateMod)
+
+	want := &UserState{
+		Channel: "lirik",
+		User: &User{
+			DisplayName: "julezdev",
+			Color:       "#FFFFFF",
+			Badges:      map[string]int{"moderator": 1},
+			Mod:         true,
+			UserType:    "mod",
+		},
+		EmoteSets: []string{"0", "33563"},
+		Raw:       msg,
+	}
+
+	got, err := ParseUserState(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseUserState() = %v, want %v", got, want)
+	}
+}
+
+func TestConnection_UserState(t *testing.T) {
+	var (
+		global   *UserState
+		channels []*UserState
+	)
+
+	conn := &Connection{
+		config:      &Config{},
+		client:      NewClient("julezdev", "oauth:123", &Config{}),
+		handlerLock: &sync.RWMutex{},
+		ircHandler: &IRCHandler{
+			OnGlobalUserState: func(_ *Connection, us *UserState) { global = us },
+		},
+		channelHandler: map[string]Handler{
+			"lirik": &ChannelHandler{
+				OnUserState: func(_ *Connection, us *UserState) { channels = append(channels, us) },
+			},
+		},
+	}
+
+	if conn.Self() != nil {
+		t.Fatal("Self() returned a user before a GLOBALUSERSTATE was received")
+	}
+
+	for _, line := range []string{globalUserState, userStateMod} {
+		if err := conn.handleLine(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	self := conn.Self()
+	if self == nil || self.User.ID != "530594933" || self.User.Name != "julezdev" || len(self.EmoteSets) != 4 {
+		t.Fatalf("Self() = %+v, want the global user state", self)
+	}
+
+	if global != self {
+		t.Errorf("OnGlobalUserState() = %+v, want %+v", global, self)
+	}
+
+	state, ok := conn.UserState("Lirik")
+	if !ok || !state.User.IsMod() || state.User.IsVIP() || state.User.ID != "530594933" {
+		t.Fatalf("UserState() = %+v, want a moderator", state)
+	}
+
+	if len(channels) != 1 || channels[0] != state {
+		t.Errorf("OnUserState() = %v, want %v", channels, state)
+	}
+
+	if !conn.isModerator("lirik") || conn.isModerator("xqcow") {
+		t.Error("isModerator() does not match the user states")
+	}
+}